@@ -9,6 +9,7 @@ import {
   startPolling,
   togglePolling,
   pausePolling,
+  setNextSlideDelay,
 } from "./polling";
 import { preventSleep } from "./wakelock";
 import {
@@ -18,6 +19,8 @@ import {
   toggleImageOverlay,
 } from "./menu";
 import { initClock } from "./clock";
+import { initRemote } from "./remote";
+import { initPush, isPushConnected } from "./push";
 import type { TimeFormat } from "./clock";
 
 ("use strict");
@@ -28,6 +31,7 @@ interface HTMXEvent extends Event {
     successful: boolean;
     parameters: FormData;
     method: string;
+    xhr: XMLHttpRequest;
   };
 }
 
@@ -36,6 +40,7 @@ interface HTMXEvent extends Event {
  * @property debug - Enable debug mode
  * @property debugVerbose - Enable verbose debug logging
  * @property version - Version string
+ * @property reloadTimeStamp - When the config was last loaded
  * @property params - Additional configuration parameters
  * @property refresh - Refresh interval in seconds
  * @property disableScreensaver - Whether to prevent screen sleeping
@@ -51,6 +56,7 @@ type KioskData = {
   debug: boolean;
   debugVerbose: boolean;
   version: string;
+  reloadTimeStamp: string;
   params: Record<string, unknown>;
   refresh: number;
   disableScreensaver: boolean;
@@ -99,6 +105,9 @@ const prevImageMenuButton = htmx.find(
 const moreInfoButton = htmx.find(
   ".navigation--more-info",
 ) as HTMLElement | null;
+const previousImageInteraction = htmx.find(
+  "#navigation-interaction-area--previous-image",
+) as HTMLElement | null;
 const offlineSVG = htmx.find("#offline") as HTMLElement | null;
 
 let requestInFlight = false;
@@ -113,6 +122,8 @@ let requestInFlight = false;
  * - Fullscreen capability
  * - Image polling
  * - Navigation menu
+ * - Remote control commands
+ * - Push channel
  * - Event listeners
  * @returns Promise<void>
  */
@@ -170,6 +181,10 @@ async function init(): Promise<void> {
   } else {
     console.error("Menu buttons not found");
   }
+
+  initRemote(kiosk, previousImageInteraction);
+  initPush(kioskData.version, kioskData.reloadTimeStamp, kioskQueries);
+
   addEventListeners();
 }
 
@@ -210,6 +225,7 @@ function handleInfoKeyPress(): void {
  * - Keyboard shortcuts (space and 'i' keys)
  * - Fullscreen toggle functionality
  * - Image overlay controls
+ * - Group slide scheduling
  * - HTMX error handling for offline states
  * - Server connectivity monitoring
  */
@@ -240,6 +256,14 @@ function addEventListeners(): void {
   // More info overlay
   moreInfoButton?.addEventListener("click", () => toggleImageOverlay());
 
+  // Devices in a group request slides when the server schedules them
+  htmx.on("htmx:beforeSwap", function (e: HTMXEvent) {
+    const slideDelay = e.detail.xhr?.getResponseHeader("kiosk-slide-delay");
+    if (slideDelay) {
+      setNextSlideDelay(Number(slideDelay));
+    }
+  });
+
   // Unable to send ajax. probably offline.
   htmx.on("htmx:sendError", () => {
     releaseRequestLock();
@@ -346,12 +370,14 @@ function checkHistoryExists(e: HTMXEvent): void {
 type BrowserData = {
   client_width: number;
   client_height: number;
+  client_dpr: number;
 };
 
 function clientData(): BrowserData {
   return {
     client_width: window.innerWidth,
     client_height: window.innerHeight,
+    client_dpr: window.devicePixelRatio || 1,
   };
 }
 
@@ -416,4 +442,5 @@ export {
   releaseRequestLock,
   checkHistoryExists,
   clientData,
+  isPushConnected,
 };
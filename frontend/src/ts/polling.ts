@@ -8,6 +8,8 @@ let pausedTime: number | null = null;
 let isPaused = false;
 
 let pollInterval: number;
+let slideInterval: number;
+let nextSlideDelay: number | null = null;
 let kioskElement: HTMLElement | null;
 let menuElement: HTMLElement | null;
 let menuPausePlayButton: HTMLElement | null;
@@ -35,13 +37,13 @@ function updateKiosk(timestamp: number) {
   }
 
   const elapsed = timestamp - lastPollTime!;
-  const progress = Math.min(elapsed / pollInterval, 1);
+  const progress = Math.min(elapsed / slideInterval, 1);
 
   if (progressBarElement) {
     progressBarElement.style.width = `${progress * 100}%`;
   }
 
-  if (elapsed >= pollInterval) {
+  if (elapsed >= slideInterval) {
     htmx.trigger(kioskElement as HTMLElement, "kiosk-new-image");
     lastPollTime = timestamp;
     stopPolling();
@@ -51,10 +53,42 @@ function updateKiosk(timestamp: number) {
   animationFrameId = requestAnimationFrame(updateKiosk);
 }
 
+/**
+ * Set when the next slide should be requested
+ * @param delay - Milliseconds to wait, as scheduled by the server for devices in a group
+ * @description Used once, by the next call to startPolling.
+ */
+function setNextSlideDelay(delay: number) {
+  nextSlideDelay = delay >= 0 ? delay : null;
+}
+
+/**
+ * Get the polling interval for the current slide
+ * @description The server's schedule for devices in a group takes priority. Otherwise slides
+ * can override the refresh interval with a data-duration attribute (in seconds), e.g. panoramas
+ * that scroll for longer than the refresh rate.
+ * @returns The interval in milliseconds
+ */
+function currentSlideInterval(): number {
+  if (nextSlideDelay !== null) {
+    const delay = nextSlideDelay;
+    nextSlideDelay = null;
+    return delay;
+  }
+
+  const frames = htmx.findAll(".frame");
+  const latestFrame = frames[frames.length - 1] as HTMLElement | undefined;
+  const duration = Number(latestFrame?.dataset.duration);
+
+  return duration > 0 ? duration * 1000 : pollInterval;
+}
+
 /**
  * Start the polling process to fetch new images
  */
 function startPolling() {
+  slideInterval = currentSlideInterval();
+
   progressBarElement = htmx.find(".progress--bar") as HTMLElement | null;
   progressBarElement?.classList.remove("progress--bar-paused");
 
@@ -131,4 +165,5 @@ export {
   pausePolling,
   resumePolling,
   togglePolling,
+  setNextSlideDelay,
 };
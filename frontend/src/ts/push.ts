@@ -0,0 +1,102 @@
+/**
+ * @module push
+ * Module for the Server-Sent Events push channel between Kiosk and this device.
+ * While connected the server pushes reloads, remote commands and sleep changes,
+ * and the refresh check polling is skipped. If the connection drops Kiosk falls back
+ * to polling until it reconnects.
+ */
+
+import htmx from "htmx.org";
+
+const MIN_RECONNECT_DELAY = 5000 as const;
+const MAX_RECONNECT_DELAY = 60000 as const;
+
+let eventSource: EventSource | null = null;
+let pushURL: string;
+let connected = false;
+let reconnectDelay: number = MIN_RECONNECT_DELAY;
+
+/**
+ * Initialize the push channel
+ * @param version - The Kiosk version the page was rendered with
+ * @param reloadTimeStamp - When the config the page was rendered with was loaded
+ * @param params - The kiosk URL params, so the server uses the same client and password
+ */
+function initPush(
+  version: string,
+  reloadTimeStamp: string,
+  params: NodeListOf<Element>,
+): void {
+  if (!("EventSource" in window)) {
+    console.debug("Server-Sent Events not supported, using polling");
+    return;
+  }
+
+  const query = new URLSearchParams({ version, reload: reloadTimeStamp });
+
+  params.forEach((param) => {
+    if (param instanceof HTMLInputElement && param.name && param.value) {
+      query.append(param.name, param.value);
+    }
+  });
+
+  pushURL = `/events?${query.toString()}`;
+
+  connect();
+}
+
+/**
+ * Open the push connection
+ * @description The browser reconnects dropped connections by itself. Connections the
+ * browser gives up on (e.g. the server was unreachable) are reopened with a backoff.
+ */
+function connect(): void {
+  eventSource = new EventSource(pushURL);
+
+  eventSource.addEventListener("open", () => {
+    connected = true;
+    reconnectDelay = MIN_RECONNECT_DELAY;
+  });
+
+  eventSource.addEventListener("error", () => {
+    connected = false;
+
+    if (eventSource?.readyState !== EventSource.CLOSED) return;
+
+    eventSource = null;
+    setTimeout(connect, reconnectDelay);
+    reconnectDelay = Math.min(reconnectDelay * 2, MAX_RECONNECT_DELAY);
+  });
+
+  eventSource.addEventListener("reload", () => {
+    eventSource?.close();
+    location.reload();
+  });
+
+  eventSource.addEventListener("commands", (e: MessageEvent) => {
+    try {
+      htmx.trigger(document.body, "kiosk-commands", JSON.parse(e.data));
+    } catch (error) {
+      console.error("Invalid commands from server:", error);
+    }
+  });
+
+  eventSource.addEventListener("sleep", (e: MessageEvent) => {
+    try {
+      const { sleeping } = JSON.parse(e.data);
+      document.body.classList.toggle("sleep", sleeping === true);
+    } catch (error) {
+      console.error("Invalid sleep state from server:", error);
+    }
+  });
+}
+
+/**
+ * Whether the push channel is connected
+ * @returns true when updates are being pushed and polling can be skipped
+ */
+function isPushConnected(): boolean {
+  return connected;
+}
+
+export { initPush, isPushConnected };
@@ -0,0 +1,91 @@
+/**
+ * @module remote
+ * Module for running commands sent to this device through the remote control API
+ */
+
+import htmx from "htmx.org";
+import { pausePolling, resumePolling } from "./polling";
+
+/**
+ * A command sent to this device
+ * @property action - What the device should do
+ * @property assetID - The asset to show, only sent with show_asset
+ */
+type RemoteCommand = {
+  action:
+    | "next"
+    | "previous"
+    | "pause"
+    | "resume"
+    | "sleep"
+    | "wake"
+    | "show_asset"
+    | "reload";
+  assetID?: string;
+};
+
+interface RemoteCommandsEvent extends Event {
+  detail: {
+    commands?: RemoteCommand[];
+  };
+}
+
+let kioskElement: HTMLElement | null;
+let previousImageElement: HTMLElement | null;
+
+/**
+ * Initialize remote control
+ * @param kiosk - The element that requests new images
+ * @param previousImage - The element that requests the previous image
+ */
+function initRemote(
+  kiosk: HTMLElement | null,
+  previousImage: HTMLElement | null,
+): void {
+  kioskElement = kiosk;
+  previousImageElement = previousImage;
+
+  htmx.on("kiosk-commands", (e: Event) => {
+    const commands = (e as RemoteCommandsEvent).detail?.commands ?? [];
+    commands.forEach(runCommand);
+  });
+}
+
+/**
+ * Run a single remote command
+ * @param command - The command to run
+ * @description show_asset requests a new image, the server answers with the asset it was asked to show
+ */
+function runCommand(command: RemoteCommand): void {
+  switch (command.action) {
+    case "next":
+    case "show_asset":
+      kioskElement && htmx.trigger(kioskElement, "kiosk-new-image");
+      break;
+    case "previous":
+      previousImageElement &&
+        htmx.trigger(previousImageElement, "kiosk-prev-image");
+      break;
+    case "pause":
+      pausePolling(false);
+      break;
+    case "resume":
+      resumePolling(true);
+      break;
+    case "sleep":
+      document.body.classList.add("sleep");
+      pausePolling(false);
+      break;
+    case "wake":
+      document.body.classList.remove("sleep");
+      kioskElement && htmx.trigger(kioskElement, "kiosk-new-image");
+      break;
+    case "reload":
+      location.reload();
+      break;
+    default:
+      console.warn("Unknown remote command", command);
+  }
+}
+
+export { initRemote };
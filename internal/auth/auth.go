@@ -0,0 +1,292 @@
+// Package auth authenticates requests to Kiosk.
+//
+// Requests are authenticated with the Kiosk password, an API token or a session cookie set by the login page.
+// Passwords can be stored in config.yaml as bcrypt hashes and tokens are only ever stored hashed.
+// Secrets are compared in constant time. Tokens can be bound to a client profile or a device and revoked
+// from the admin dashboard. Tokens and sessions are persisted to a JSON file so they survive restarts.
+package auth
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/charmbracelet/log"
+	"golang.org/x/crypto/bcrypt"
+)
+
+var (
+	mu        sync.RWMutex
+	tokens    = make(map[string]*Token)
+	sessions  = make(map[string]*Session)
+	storePath string
+	dirty     bool
+
+	// verified holds a digest of the last secret that matched each bcrypt hash.
+	// bcrypt is deliberately slow and devices send their password or token with every request,
+	// so requests repeating a secret that has already been checked are compared against the digest instead.
+	// Not persisted.
+	verified = make(map[string][sha256.Size]byte)
+
+	// fingerprintKey keys the HMAC used to fingerprint passwords. It is kept in its own file next to the
+	// tokens file, so the fingerprints stored with sessions can not be used to guess the password on their own.
+	fingerprintKey = newFingerprintKey()
+)
+
+// fingerprintKeySize is the size of fingerprintKey in bytes
+const fingerprintKeySize = 32
+
+// store is the layout of the JSON file tokens and sessions are persisted to
+type store struct {
+	Tokens   []Token   `json:"tokens"`
+	Sessions []Session `json:"sessions"`
+}
+
+// Credential is a secret set in config.yaml, either in plain text or as a bcrypt hash.
+// The hash is used when both are set.
+type Credential struct {
+	Secret string
+	Hash   string
+}
+
+// IsSet reports whether the credential has been configured.
+func (c Credential) IsSet() bool {
+	return c.Secret != "" || c.Hash != ""
+}
+
+// Check reports whether given matches the credential.
+func (c Credential) Check(given string) bool {
+	switch {
+	case given == "":
+		return false
+	case c.Hash != "":
+		return checkHash(c.Hash, given)
+	case c.Secret != "":
+		return subtle.ConstantTimeCompare([]byte(given), []byte(c.Secret)) == 1
+	default:
+		return false
+	}
+}
+
+// Matches reports whether both credentials are set to the same secret.
+// Only one side can be checked against a hash, so two different hashes never match.
+func (c Credential) Matches(other Credential) bool {
+	switch {
+	case !c.IsSet() || !other.IsSet():
+		return false
+	case c.Hash != "" && other.Hash != "":
+		return c.Hash == other.Hash
+	case c.Hash != "":
+		return c.Check(other.Secret)
+	case other.Hash != "":
+		return other.Check(c.Secret)
+	default:
+		return subtle.ConstantTimeCompare([]byte(c.Secret), []byte(other.Secret)) == 1
+	}
+}
+
+// fingerprint identifies the configured value of the credential without revealing it,
+// so sessions started with a password end when the password is changed.
+// The caller must hold the lock.
+func (c Credential) fingerprint() string {
+	mac := hmac.New(sha256.New, fingerprintKey)
+	mac.Write([]byte("hash:" + c.Hash + "\x00secret:" + c.Secret))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// newFingerprintKey returns a random key for fingerprinting passwords.
+func newFingerprintKey() []byte {
+	key := make([]byte, fingerprintKeySize)
+	if _, err := rand.Read(key); err != nil {
+		panic(err)
+	}
+	return key
+}
+
+// keyPath returns the path of the file fingerprintKey is kept in.
+func keyPath(path string) string {
+	return path + ".key"
+}
+
+// loadFingerprintKey reads fingerprintKey from its file next to path,
+// creating the file with a new key if it does not exist. The caller must hold the lock.
+func loadFingerprintKey(path string) error {
+	key, err := os.ReadFile(keyPath(path))
+	if err == nil && len(key) == fingerprintKeySize {
+		fingerprintKey = key
+		return nil
+	}
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+
+	key = newFingerprintKey()
+	if err := os.WriteFile(keyPath(path), key, 0o600); err != nil {
+		return err
+	}
+
+	fingerprintKey = key
+
+	return nil
+}
+
+// checkHash reports whether given matches the bcrypt hash.
+func checkHash(hash, given string) bool {
+	digest := sha256.Sum256([]byte(given))
+
+	mu.RLock()
+	last, ok := verified[hash]
+	mu.RUnlock()
+
+	if ok && subtle.ConstantTimeCompare(digest[:], last[:]) == 1 {
+		return true
+	}
+
+	if bcrypt.CompareHashAndPassword([]byte(hash), []byte(given)) != nil {
+		return false
+	}
+
+	mu.Lock()
+	verified[hash] = digest
+	mu.Unlock()
+
+	return true
+}
+
+// hashSecret returns the bcrypt hash of secret.
+func hashSecret(secret string) (string, error) {
+	hash, err := bcrypt.GenerateFromPassword([]byte(secret), bcrypt.DefaultCost)
+	return string(hash), err
+}
+
+// randomHex returns n random bytes encoded as hex.
+func randomHex(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// Load reads tokens and sessions from the JSON file at path and uses path for future saves.
+// A missing file is not an error, Kiosk simply starts without tokens.
+// The key used to fingerprint passwords is read from, or created in, the same directory.
+func Load(path string) error {
+	mu.Lock()
+	defer mu.Unlock()
+
+	storePath = path
+
+	if err := loadFingerprintKey(path); err != nil {
+		return err
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+		return err
+	}
+
+	var stored store
+	if err := json.Unmarshal(data, &stored); err != nil {
+		return err
+	}
+
+	tokens = make(map[string]*Token, len(stored.Tokens))
+	for _, token := range stored.Tokens {
+		if token.ID == "" || token.Hash == "" {
+			continue
+		}
+		tokens[token.ID] = &token
+	}
+
+	sessions = make(map[string]*Session, len(stored.Sessions))
+	for _, session := range stored.Sessions {
+		if session.Hash == "" {
+			continue
+		}
+		sessions[session.Hash] = &session
+	}
+
+	return nil
+}
+
+// Save writes tokens and sessions to their JSON file, removing sessions that have expired.
+// The file is written to a temporary file first and renamed so a crash never leaves a partial file.
+func Save() error {
+	mu.Lock()
+	defer mu.Unlock()
+
+	if storePath == "" || !dirty {
+		return nil
+	}
+
+	now := time.Now()
+	for hash, session := range sessions {
+		if now.After(session.ExpiresAt) {
+			delete(sessions, hash)
+		}
+	}
+
+	stored := store{
+		Tokens:   sortedTokens(),
+		Sessions: make([]Session, 0, len(sessions)),
+	}
+	for _, session := range sessions {
+		stored.Sessions = append(stored.Sessions, *session)
+	}
+
+	data, err := json.MarshalIndent(stored, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(storePath), 0o755); err != nil {
+		return err
+	}
+
+	tmp := storePath + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		return err
+	}
+
+	if err := os.Rename(tmp, storePath); err != nil {
+		return err
+	}
+
+	dirty = false
+
+	return nil
+}
+
+// Persist saves tokens and sessions every interval until the context is cancelled.
+func Persist(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := Save(); err != nil {
+				log.Error("saving tokens", "path", storePath, "err", err)
+			}
+		}
+	}
+}
@@ -0,0 +1,102 @@
+package auth
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"time"
+)
+
+// SessionTTL is how long a login lasts
+const SessionTTL = 30 * 24 * time.Hour
+
+// Session is a login started from the login page.
+// The session ID is only stored in the device's cookie, Kiosk keeps a hash of it.
+type Session struct {
+	// Hash the SHA-256 hash of the session ID
+	Hash string `json:"hash"`
+	// TokenID the token used to log in, empty when the Kiosk password was used
+	TokenID string `json:"tokenId,omitempty"`
+	// Password the fingerprint of the password used to log in
+	Password string `json:"password,omitempty"`
+	// ExpiresAt when the session ends
+	ExpiresAt time.Time `json:"expiresAt"`
+}
+
+// NewPasswordSession starts a session for a login with the Kiosk password and returns its ID.
+// The session ends if the password is changed.
+func NewPasswordSession(password Credential, now time.Time) (string, error) {
+	mu.RLock()
+	fingerprint := password.fingerprint()
+	mu.RUnlock()
+
+	return newSession(Session{Password: fingerprint}, now)
+}
+
+// NewTokenSession starts a session for a login with the token with the given ID and returns its ID.
+// The session ends if the token is revoked.
+func NewTokenSession(tokenID string, now time.Time) (string, error) {
+	return newSession(Session{TokenID: tokenID}, now)
+}
+
+// newSession stores session and returns its ID.
+func newSession(session Session, now time.Time) (string, error) {
+	id, err := randomHex(32)
+	if err != nil {
+		return "", err
+	}
+
+	session.Hash = sessionHash(id)
+	session.ExpiresAt = now.Add(SessionTTL)
+
+	mu.Lock()
+	sessions[session.Hash] = &session
+	dirty = true
+	mu.Unlock()
+
+	return id, nil
+}
+
+// ValidSession returns a copy of the session with the given ID if it has not expired,
+// its token has not been revoked and, for password logins, password has not changed.
+func ValidSession(id string, password Credential, now time.Time) (Session, bool) {
+	if id == "" {
+		return Session{}, false
+	}
+
+	mu.RLock()
+	defer mu.RUnlock()
+
+	session, ok := sessions[sessionHash(id)]
+	if !ok || now.After(session.ExpiresAt) {
+		return Session{}, false
+	}
+
+	if session.TokenID != "" {
+		token, ok := tokens[session.TokenID]
+		if !ok || token.Revoked {
+			return Session{}, false
+		}
+	} else if !password.IsSet() || session.Password != password.fingerprint() {
+		return Session{}, false
+	}
+
+	return *session, true
+}
+
+// EndSession ends the session with the given ID.
+func EndSession(id string) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	hash := sessionHash(id)
+	if _, ok := sessions[hash]; ok {
+		delete(sessions, hash)
+		dirty = true
+	}
+}
+
+// sessionHash returns the hash a session is stored under.
+func sessionHash(id string) string {
+	sum := sha256.Sum256([]byte(id))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,198 @@
+package auth
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// resetStore forgets every token and session
+func resetStore(t *testing.T) {
+	t.Helper()
+
+	mu.Lock()
+	tokens = make(map[string]*Token)
+	sessions = make(map[string]*Session)
+	verified = make(map[string][32]byte)
+	fingerprintKey = newFingerprintKey()
+	storePath = ""
+	dirty = false
+	mu.Unlock()
+}
+
+// TestCredentialCheck tests plain text and hashed credentials
+func TestCredentialCheck(t *testing.T) {
+	resetStore(t)
+
+	hash, err := bcrypt.GenerateFromPassword([]byte("1234"), bcrypt.MinCost)
+	assert.NoError(t, err)
+
+	testCases := []struct {
+		name       string
+		credential Credential
+		given      string
+		want       bool
+	}{
+		{name: "plain text match", credential: Credential{Secret: "1234"}, given: "1234", want: true},
+		{name: "plain text mismatch", credential: Credential{Secret: "1234"}, given: "12345", want: false},
+		{name: "hash match", credential: Credential{Hash: string(hash)}, given: "1234", want: true},
+		{name: "hash match again", credential: Credential{Hash: string(hash)}, given: "1234", want: true},
+		{name: "hash mismatch", credential: Credential{Hash: string(hash)}, given: "4321", want: false},
+		{name: "hash preferred", credential: Credential{Secret: "4321", Hash: string(hash)}, given: "4321", want: false},
+		{name: "empty given", credential: Credential{Secret: "1234"}, given: "", want: false},
+		{name: "not set", credential: Credential{}, given: "1234", want: false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.want, tc.credential.Check(tc.given))
+		})
+	}
+}
+
+// TestCredentialMatches tests the admin password can be compared with the Kiosk password however either is set
+func TestCredentialMatches(t *testing.T) {
+	resetStore(t)
+
+	hash, err := bcrypt.GenerateFromPassword([]byte("1234"), bcrypt.MinCost)
+	assert.NoError(t, err)
+
+	testCases := []struct {
+		name  string
+		a, b  Credential
+		match bool
+	}{
+		{name: "same plain text", a: Credential{Secret: "1234"}, b: Credential{Secret: "1234"}, match: true},
+		{name: "different plain text", a: Credential{Secret: "1234"}, b: Credential{Secret: "4321"}, match: false},
+		{name: "plain text and its hash", a: Credential{Secret: "1234"}, b: Credential{Hash: string(hash)}, match: true},
+		{name: "hash and its plain text", a: Credential{Hash: string(hash)}, b: Credential{Secret: "1234"}, match: true},
+		{name: "hash and other plain text", a: Credential{Hash: string(hash)}, b: Credential{Secret: "4321"}, match: false},
+		{name: "same hash", a: Credential{Hash: string(hash)}, b: Credential{Hash: string(hash)}, match: true},
+		{name: "not set", a: Credential{}, b: Credential{}, match: false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.match, tc.a.Matches(tc.b))
+		})
+	}
+}
+
+// TestTokens tests tokens are verified against their hash, bound to their client and device, and can be revoked
+func TestTokens(t *testing.T) {
+	resetStore(t)
+
+	now := time.Now()
+
+	plain, token, err := CreateToken("Kitchen", "kitchen", "device-1")
+	assert.NoError(t, err)
+	assert.NotContains(t, token.Hash, plain, "only a hash of the token should be kept")
+	assert.True(t, HasTokens())
+
+	verifiedToken, ok := VerifyToken(plain, now)
+	assert.True(t, ok)
+	assert.Equal(t, "kitchen", verifiedToken.Client)
+	assert.Equal(t, "device-1", verifiedToken.DeviceID)
+	assert.Equal(t, now, verifiedToken.LastUsed)
+
+	_, ok = VerifyToken(plain, now)
+	assert.True(t, ok, "tokens should verify more than once")
+
+	_, ok = VerifyToken(plain+"0", now)
+	assert.False(t, ok, "a wrong secret should not verify")
+
+	_, ok = VerifyToken(tokenPrefix+"unknown_secret", now)
+	assert.False(t, ok)
+
+	_, ok = VerifyToken("not-a-token", now)
+	assert.False(t, ok)
+
+	assert.True(t, RevokeToken(token.ID))
+	assert.False(t, RevokeToken("unknown"))
+
+	_, ok = VerifyToken(plain, now)
+	assert.False(t, ok, "revoked tokens should not verify")
+	assert.False(t, HasTokens())
+}
+
+// TestSessions tests sessions end when they expire, their token is revoked or the password changes
+func TestSessions(t *testing.T) {
+	resetStore(t)
+
+	now := time.Now()
+	password := Credential{Secret: "1234"}
+
+	passwordSession, err := NewPasswordSession(password, now)
+	assert.NoError(t, err)
+
+	_, ok := ValidSession(passwordSession, password, now)
+	assert.True(t, ok)
+
+	_, ok = ValidSession(passwordSession, password, now.Add(SessionTTL+time.Second))
+	assert.False(t, ok, "expired sessions should not be valid")
+
+	_, ok = ValidSession(passwordSession, Credential{Secret: "4321"}, now)
+	assert.False(t, ok, "sessions should end when the password changes")
+
+	_, token, err := CreateToken("Lounge", "", "")
+	assert.NoError(t, err)
+
+	tokenSession, err := NewTokenSession(token.ID, now)
+	assert.NoError(t, err)
+
+	session, ok := ValidSession(tokenSession, Credential{}, now)
+	assert.True(t, ok)
+	assert.Equal(t, token.ID, session.TokenID)
+
+	RevokeToken(token.ID)
+	_, ok = ValidSession(tokenSession, Credential{}, now)
+	assert.False(t, ok, "sessions should end when their token is revoked")
+
+	EndSession(passwordSession)
+	_, ok = ValidSession(passwordSession, password, now)
+	assert.False(t, ok, "ended sessions should not be valid")
+
+	_, ok = ValidSession("", password, now)
+	assert.False(t, ok)
+}
+
+// TestLoadSave tests tokens and sessions survive a restart
+func TestLoadSave(t *testing.T) {
+	resetStore(t)
+
+	path := filepath.Join(t.TempDir(), "tokens.json")
+	assert.NoError(t, Load(path), "a missing file should not be an error")
+
+	info, err := os.Stat(keyPath(path))
+	assert.NoError(t, err, "the fingerprint key should be created")
+	assert.Equal(t, os.FileMode(0o600), info.Mode().Perm())
+
+	plain, _, err := CreateToken("Hall", "", "")
+	assert.NoError(t, err)
+
+	password := Credential{Secret: "1234"}
+	sessionID, err := NewPasswordSession(password, time.Now())
+	assert.NoError(t, err)
+
+	assert.NoError(t, Save())
+
+	data, err := os.ReadFile(path)
+	assert.NoError(t, err)
+	sum := sha256.Sum256([]byte("hash:\x00secret:1234"))
+	assert.NotContains(t, string(data), hex.EncodeToString(sum[:]), "passwords should not be stored as a plain hash")
+
+	resetStore(t)
+	assert.NoError(t, Load(path))
+
+	_, ok := VerifyToken(plain, time.Now())
+	assert.True(t, ok)
+
+	_, ok = ValidSession(sessionID, password, time.Now())
+	assert.True(t, ok)
+}
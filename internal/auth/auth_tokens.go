@@ -0,0 +1,197 @@
+package auth
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"slices"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	// tokenPrefix starts every token so they are easy to recognise, e.g. in logs or config files
+	tokenPrefix = "kiosk_"
+	// lastUsedPrecision is how often a token's last use is recorded, so busy devices do not keep the file dirty
+	lastUsedPrecision = time.Minute
+)
+
+// Token is an API token. Only a hash of the token is kept, the token itself is shown once when it is created.
+type Token struct {
+	// ID identifies the token. It is the public part of the token.
+	ID string `json:"id"`
+	// Name the friendly name given to the token
+	Name string `json:"name"`
+	// Client the client profile requests using the token must use, if any
+	Client string `json:"client,omitempty"`
+	// DeviceID the device requests using the token are from, if any
+	DeviceID string `json:"deviceId,omitempty"`
+	// Hash the bcrypt hash of the secret part of the token
+	Hash string `json:"hash"`
+	// CreatedAt and LastUsed when the token was created and most recently used
+	CreatedAt time.Time `json:"createdAt"`
+	LastUsed  time.Time `json:"lastUsed"`
+	// Revoked is true once the token can no longer be used
+	Revoked bool `json:"revoked"`
+}
+
+// CreateToken creates a token, optionally bound to a client profile and device.
+// It returns the token, which is not stored and can not be shown again.
+func CreateToken(name, client, deviceID string) (string, Token, error) {
+	id, err := randomHex(8)
+	if err != nil {
+		return "", Token{}, err
+	}
+
+	secret, err := randomHex(24)
+	if err != nil {
+		return "", Token{}, err
+	}
+
+	hash, err := hashSecret(secret)
+	if err != nil {
+		return "", Token{}, err
+	}
+
+	token := Token{
+		ID:        id,
+		Name:      name,
+		Client:    client,
+		DeviceID:  deviceID,
+		Hash:      hash,
+		CreatedAt: time.Now(),
+	}
+
+	mu.Lock()
+	tokens[id] = &token
+	dirty = true
+	mu.Unlock()
+
+	return tokenPrefix + id + "_" + secret, token, nil
+}
+
+// VerifyToken checks the given token and records that it was used at now.
+// It returns a copy of the token if it exists and has not been revoked.
+func VerifyToken(given string, now time.Time) (Token, bool) {
+	id, secret, ok := strings.Cut(strings.TrimPrefix(given, tokenPrefix), "_")
+	if !ok || !strings.HasPrefix(given, tokenPrefix) {
+		return Token{}, false
+	}
+
+	digest := sha256.Sum256([]byte(secret))
+
+	mu.RLock()
+	token, ok := tokens[id]
+	var hash string
+	var last [sha256.Size]byte
+	var seen bool
+	if ok {
+		hash = token.Hash
+		last, seen = verified[hash]
+	}
+	mu.RUnlock()
+
+	if !ok {
+		return Token{}, false
+	}
+
+	if !seen || subtle.ConstantTimeCompare(digest[:], last[:]) != 1 {
+		if bcrypt.CompareHashAndPassword([]byte(hash), []byte(secret)) != nil {
+			return Token{}, false
+		}
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	// the token may have been revoked while its hash was being checked
+	token, ok = tokens[id]
+	if !ok || token.Revoked {
+		return Token{}, false
+	}
+
+	verified[hash] = digest
+
+	if now.Sub(token.LastUsed) >= lastUsedPrecision {
+		token.LastUsed = now
+		dirty = true
+	}
+
+	return *token, true
+}
+
+// GetToken returns a copy of the token with the given ID.
+func GetToken(id string) (Token, bool) {
+	mu.RLock()
+	defer mu.RUnlock()
+
+	token, ok := tokens[id]
+	if !ok {
+		return Token{}, false
+	}
+
+	return *token, true
+}
+
+// RevokeToken revokes the token with the given ID and ends the sessions started with it.
+// It reports whether the token exists.
+func RevokeToken(id string) bool {
+	mu.Lock()
+	defer mu.Unlock()
+
+	token, ok := tokens[id]
+	if !ok {
+		return false
+	}
+
+	token.Revoked = true
+	delete(verified, token.Hash)
+
+	for hash, session := range sessions {
+		if session.TokenID == id {
+			delete(sessions, hash)
+		}
+	}
+
+	dirty = true
+
+	return true
+}
+
+// HasTokens reports whether any tokens can be used.
+func HasTokens() bool {
+	mu.RLock()
+	defer mu.RUnlock()
+
+	for _, token := range tokens {
+		if !token.Revoked {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Tokens returns a copy of every token, most recently created first.
+func Tokens() []Token {
+	mu.RLock()
+	defer mu.RUnlock()
+
+	return sortedTokens()
+}
+
+// sortedTokens returns copies of the tokens, most recently created first.
+// The caller must hold the lock.
+func sortedTokens() []Token {
+	all := make([]Token, 0, len(tokens))
+	for _, token := range tokens {
+		all = append(all, *token)
+	}
+
+	slices.SortFunc(all, func(a, b Token) int {
+		return b.CreatedAt.Compare(a.CreatedAt)
+	})
+
+	return all
+}
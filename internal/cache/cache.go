@@ -3,6 +3,7 @@ package cache
 import (
 	"crypto/sha256"
 	"fmt"
+	"sync/atomic"
 	"time"
 
 	gocache "github.com/patrickmn/go-cache"
@@ -14,8 +15,21 @@ var (
 
 	defaultExpiration = 5 * time.Minute
 	cleanupInterval   = 10 * time.Minute
+
+	// hits and misses count cache lookups since the cache was last flushed
+	hits   atomic.Uint64
+	misses atomic.Uint64
 )
 
+// Stats holds cache statistics
+type Stats struct {
+	// Items the number of items in the cache, including expired items not yet cleaned up
+	Items int
+	// Hits and Misses the number of lookups that did and did not find an item since the last flush
+	Hits   uint64
+	Misses uint64
+}
+
 // init initializes the kiosk cache with a 5 minute default expiration and 10 minute cleanup interval.
 // The cleanup interval determines how often expired items are removed from the cache.
 func init() {
@@ -27,6 +41,8 @@ func init() {
 // This operation cannot be undone.
 func Flush() {
 	kioskCache.Flush()
+	hits.Store(0)
+	misses.Store(0)
 }
 
 // ItemCount returns the number of items currently stored in the cache,
@@ -51,11 +67,32 @@ func ApiCacheKey(apiUrl, deviceID string) string {
 	return fmt.Sprintf("%x", sha256.Sum256([]byte(key)))
 }
 
+// QualityCacheKey generates a cache key for the quality scores of an asset by combining
+// the asset ID with a ':quality' suffix. Scores are stored per asset so they are shared between devices.
+func QualityCacheKey(assetID string) string {
+	return fmt.Sprintf("%s:quality", assetID)
+}
+
 // Get retrieves an item from the cache by key, returning the item and a boolean indicating
 // whether the key was found in the cache. If the key is not found or the item has expired,
 // the boolean will be false.
 func Get(s string) (any, bool) {
-	return kioskCache.Get(s)
+	item, found := kioskCache.Get(s)
+	if found {
+		hits.Add(1)
+	} else {
+		misses.Add(1)
+	}
+	return item, found
+}
+
+// GetStats returns the current cache statistics.
+func GetStats() Stats {
+	return Stats{
+		Items:  kioskCache.ItemCount(),
+		Hits:   hits.Load(),
+		Misses: misses.Load(),
+	}
 }
 
 // Set adds an item to the cache with the default expiration time.
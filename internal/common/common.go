@@ -9,11 +9,16 @@ import (
 	"os/signal"
 	"sync"
 	"syscall"
+	"time"
 
 	"github.com/charmbracelet/log"
+	"github.com/damongolding/immich-kiosk/internal/auth"
+	"github.com/damongolding/immich-kiosk/internal/cache"
 	"github.com/damongolding/immich-kiosk/internal/config"
+	"github.com/damongolding/immich-kiosk/internal/devices"
 	"github.com/damongolding/immich-kiosk/internal/immich"
 	"github.com/damongolding/immich-kiosk/internal/utils"
+	"github.com/damongolding/immich-kiosk/internal/weather"
 )
 
 var (
@@ -93,18 +98,61 @@ func InitializeSecret() error {
 
 // ViewImageData contains the image data and metadata for displaying an image in the view
 type ViewImageData struct {
-	ImmichImage   immich.ImmichAsset // ImmichImage contains immich asset data
-	ImageData     string             // ImageData contains the image as base64 data
-	ImageBlurData string             // ImageBlurData contains the blurred image as base64 data
-	ImageDate     string             // ImageDate contains the date of the image
+	ImmichImage     immich.ImmichAsset // ImmichImage contains immich asset data
+	ImageData       string             // ImageData contains the image as base64 data
+	ImageBlurData   string             // ImageBlurData contains the blurred image as base64 data
+	ImageBackground string             // ImageBackground contains a CSS colour or gradient used in place of the blurred image
+	ImageKenBurns   KenBurnsPath       // ImageKenBurns contains the pan and zoom path for the ken-burns effect
+	ImagePanorama   bool               // ImagePanorama is true when the image is shown as a scrolling panorama
+	ImageDuration   int                // ImageDuration is how long (in seconds) the slide is shown for, overriding Refresh when set
+	ImageDate       string             // ImageDate contains the date of the image
+}
+
+// KenBurnsViewport describes the visible part of an image during the ken-burns effect
+type KenBurnsViewport struct {
+	Scale float64 // Scale is the zoom factor, 1 shows the whole image
+	X     float64 // X is the horizontal centre of the viewport as a percentage of the image width
+	Y     float64 // Y is the vertical centre of the viewport as a percentage of the image height
+}
+
+// KenBurnsPath is the start and end viewport the ken-burns effect animates between
+type KenBurnsPath struct {
+	Start KenBurnsViewport
+	End   KenBurnsViewport
 }
 
 // ViewData contains all the data needed to render a view in the application
 type ViewData struct {
 	KioskVersion  string          // KioskVersion contains the current build version of Kiosk
 	DeviceID      string          // DeviceID contains the unique identifier for the device
+	NewDevice     bool            // NewDevice is true when DeviceID was issued by this request
 	Images        []ViewImageData // Images contains the collection of images to display in view
 	Queries       url.Values      // Queries contains the URL query parameters
 	CustomCss     []byte          // CustomCss contains custom CSS styling as bytes
 	config.Config                 // Config contains the instance configuration
 }
+
+// AdminViewData contains all the data needed to render the admin dashboard
+type AdminViewData struct {
+	KioskVersion    string                    // KioskVersion contains the current build version of Kiosk
+	CSRFToken       string                    // CSRFToken protects the dashboard's forms
+	Message         string                    // Message confirms the last action taken on the dashboard
+	Now             time.Time                 // Now is when the dashboard was rendered
+	Devices         []devices.Device          // Devices contains every known device, most recently seen first
+	Cache           cache.Stats               // Cache contains the cache statistics
+	Weather         []weather.WeatherLocation // Weather contains the status of each weather location
+	ApiErrors       []immich.ApiError         // ApiErrors contains the most recent failed Immich API calls
+	ReloadTimeStamp string                    // ReloadTimeStamp is when the config was last (re)loaded
+	Tokens          []auth.Token              // Tokens contains every API token, most recently created first
+	NewToken        string                    // NewToken is the token just created, shown only once
+	Clients         []string                  // Clients contains the names of the client profiles tokens can be bound to
+}
+
+// LoginViewData contains all the data needed to render the login page
+type LoginViewData struct {
+	KioskVersion string // KioskVersion contains the current build version of Kiosk
+	CSRFToken    string // CSRFToken protects the login and logout forms
+	Redirect     string // Redirect is where to go once logged in
+	Error        string // Error explains why the last login failed
+	LoggedIn     bool   // LoggedIn is true when the browser already has a session, showing the logout form
+}
@@ -25,6 +25,7 @@ import (
 	"errors"
 	"net/url"
 	"os"
+	"slices"
 	"sync"
 	"time"
 
@@ -43,6 +44,10 @@ const (
 	DefaultDateLayout = "02/01/2006"
 	defaultConfigFile = "config.yaml"
 
+	defaultPanoramaMinRatio = 2.5
+
+	defaultBackgroundBlurQuality = 70
+
 	AlbumOrderRandom     = "random"
 	AlbumOrderAscending  = "ascending"
 	AlbumOrderAsc        = "asc"
@@ -50,6 +55,32 @@ const (
 	AlbumOrderDescending = "descending"
 	AlbumOrderDesc       = "desc"
 	AlbumOrderNewest     = "newest"
+
+	EinkPaletteMono  = "mono"
+	EinkPaletteGrey4 = "grey4"
+	EinkPaletteACeP7 = "acep7"
+
+	EinkDitherFloydSteinberg = "floyd-steinberg"
+	EinkDitherAtkinson       = "atkinson"
+	EinkDitherNone           = "none"
+
+	EinkFormatPNG = "png"
+	EinkFormatRaw = "raw"
+
+	ImageFilterBlackAndWhite = "black-and-white"
+	ImageFilterSepia         = "sepia"
+	ImageFilterVintage       = "vintage"
+	ImageFilterVignette      = "vignette"
+	ImageFilterAutoContrast  = "auto-contrast"
+	ImageFilterWarm          = "warm"
+
+	BackgroundStyleBlur          = "blur"
+	BackgroundStyleDominantColor = "dominant-color"
+	BackgroundStyleGradient      = "gradient"
+	BackgroundStyleMatte         = "matte"
+
+	GroupModeSame      = "same"
+	GroupModeDifferent = "different"
 )
 
 // Redirect represents a URL redirection configuration with a friendly name.
@@ -80,6 +111,12 @@ type KioskSettings struct {
 	// HTTPTimeout time in seconds before an http request will timeout
 	HTTPTimeout int `json:"httpTimeout" mapstructure:"http_timeout" default:"20"`
 
+	// MaxImageMegapixels the largest image (in megapixels) Kiosk will decode
+	MaxImageMegapixels float64 `json:"maxImageMegapixels" mapstructure:"max_image_megapixels" default:"50"`
+
+	// MaxConcurrentDecodes how many images Kiosk will decode at the same time
+	MaxConcurrentDecodes int `json:"maxConcurrentDecodes" mapstructure:"max_concurrent_decodes" default:"2"`
+
 	// Cache enable/disable api call and image caching
 	Cache bool `json:"cache" mapstructure:"cache" default:"true"`
 
@@ -89,6 +126,22 @@ type KioskSettings struct {
 	// Password the password used to add authentication to the frontend
 	Password string `json:"-" mapstructure:"password" default:""`
 
+	// PasswordHash a bcrypt hash of the password, used instead of Password when set
+	PasswordHash string `json:"-" mapstructure:"password_hash" default:""`
+
+	// AdminPassword the password for the admin dashboard and remote control API.
+	// Both are disabled when it is not set or is the same as the password
+	AdminPassword string `json:"-" mapstructure:"admin_password" default:""`
+
+	// AdminPasswordHash a bcrypt hash of the admin password, used instead of AdminPassword when set
+	AdminPasswordHash string `json:"-" mapstructure:"admin_password_hash" default:""`
+
+	// TokensFile where API tokens and login sessions are stored
+	TokensFile string `json:"tokensFile" mapstructure:"tokens_file" default:"./config/tokens.json"`
+
+	// DevicesFile where the device registry is stored
+	DevicesFile string `json:"devicesFile" mapstructure:"devices_file" default:"./config/devices.json"`
+
 	// AssetWeighting use weighting when picking assets
 	AssetWeighting bool `json:"assetWeighting" mapstructure:"asset_weighting" default:"true"`
 
@@ -113,12 +166,22 @@ type Webhook struct {
 	Secret string `json:"secret" mapstructure:"secret"`
 }
 
+// DeviceBinding binds a device to a friendly name and client profile server-side.
+type DeviceBinding struct {
+	// Name is the friendly name shown for the device
+	Name string `mapstructure:"name"`
+	// Client is the client profile used by the device when its URL does not set one
+	Client string `mapstructure:"client"`
+}
+
 // ClientData represents the client-specific dimensions received from the frontend.
 type ClientData struct {
 	// Width represents the client's viewport width in pixels
 	Width int `json:"client_width" query:"client_width" form:"client_width"`
 	// Height represents the client's viewport height in pixels
 	Height int `json:"client_height" query:"client_height" form:"client_height"`
+	// DevicePixelRatio represents the ratio of physical pixels to CSS pixels on the client's screen
+	DevicePixelRatio float64 `json:"client_dpr" query:"client_dpr" form:"client_dpr"`
 }
 
 // Config represents the main configuration structure for the Immich Kiosk application.
@@ -187,6 +250,18 @@ type Config struct {
 	Theme string `json:"theme" mapstructure:"theme" query:"theme" form:"theme" default:"fade" lowercase:"true"`
 	// Layout which layout to use
 	Layout string `json:"layout" mapstructure:"layout" query:"layout" form:"layout" default:"single" lowercase:"true"`
+	// Group the slideshow group the device belongs to, devices in a group change slides at the same moment
+	Group string `json:"group" mapstructure:"group" query:"group" form:"group" default:"" lowercase:"true"`
+	// GroupMode whether devices in a group show the same asset or different assets (same | different)
+	GroupMode string `json:"groupMode" mapstructure:"group_mode" query:"group_mode" form:"group_mode" default:"same" lowercase:"true"`
+	// WallColumns and WallRows the size of the video wall the group's displays form, 0 disables the video wall
+	WallColumns int `json:"wallColumns" mapstructure:"wall_columns" query:"wall_columns" form:"wall_columns" default:"0"`
+	WallRows    int `json:"wallRows" mapstructure:"wall_rows" query:"wall_rows" form:"wall_rows" default:"0"`
+	// WallColumn and WallRow the position of the device in the video wall, counted from 1 at the top left
+	WallColumn int `json:"wallColumn" mapstructure:"wall_column" query:"wall_column" form:"wall_column" default:"1"`
+	WallRow    int `json:"wallRow" mapstructure:"wall_row" query:"wall_row" form:"wall_row" default:"1"`
+	// WallBezel the gap (in pixels) between the pictures of neighbouring displays, so lines stay straight across bezels
+	WallBezel int `json:"wallBezel" mapstructure:"wall_bezel" query:"wall_bezel" form:"wall_bezel" default:"0"`
 
 	// SleepStart when to start sleep mode
 	SleepStart string `json:"sleepStart" mapstructure:"sleep_start" query:"sleep_start" form:"sleep_start" default:""`
@@ -195,6 +270,18 @@ type Config struct {
 	// SleepIcon display sleep icon
 	SleepIcon bool `json:"sleepIcon" mapstructure:"sleep_icon" query:"sleep_icon" form:"sleep_icon" default:"true"`
 
+	// MinWidth the minimum width (in pixels) of images to display
+	MinWidth int `json:"minWidth" mapstructure:"min_width" query:"min_width" form:"min_width" default:"0"`
+	// MinHeight the minimum height (in pixels) of images to display
+	MinHeight int `json:"minHeight" mapstructure:"min_height" query:"min_height" form:"min_height" default:"0"`
+	// MinMegapixels the minimum size (in megapixels) of images to display
+	MinMegapixels float64 `json:"minMegapixels" mapstructure:"min_megapixels" query:"min_megapixels" form:"min_megapixels" default:"0"`
+	// MinAspectRatio the minimum aspect ratio (width / height) of images to display
+	MinAspectRatio float64 `json:"minAspectRatio" mapstructure:"min_aspect_ratio" query:"min_aspect_ratio" form:"min_aspect_ratio" default:"0"`
+	// MaxAspectRatio the maximum aspect ratio (width / height) of images to display
+	MaxAspectRatio float64 `json:"maxAspectRatio" mapstructure:"max_aspect_ratio" query:"max_aspect_ratio" form:"max_aspect_ratio" default:"0"`
+	// ExcludeScreenshots skip assets that look like screenshots or scanned documents
+	ExcludeScreenshots bool `json:"excludeScreenshots" mapstructure:"exclude_screenshots" query:"exclude_screenshots" form:"exclude_screenshots" default:"false"`
 	// ShowArchived allow archived image to be displayed
 	ShowArchived bool `json:"showArchived" mapstructure:"show_archived" query:"show_archived" form:"show_archived" default:"false"`
 	// Person ID of person to display
@@ -215,10 +302,42 @@ type Config struct {
 	ImageEffect string `json:"imageEffect" mapstructure:"image_effect" query:"image_effect" form:"image_effect" default:"" lowercase:"true"`
 	// ImageEffectAmount the amount of effect to apply
 	ImageEffectAmount int `json:"imageEffectAmount" mapstructure:"image_effect_amount" query:"image_effect_amount" form:"image_effect_amount" default:"120"`
+	// ImageFilters server-side filters applied (in order) to the image
+	ImageFilters []string `json:"imageFilters" mapstructure:"image_filters" query:"image_filters" form:"image_filters" default:"[]"`
+	// BlurUnnamedFaces blur the faces of people who have not been named in Immich
+	BlurUnnamedFaces bool `json:"blurUnnamedFaces" mapstructure:"blur_unnamed_faces" query:"blur_unnamed_faces" form:"blur_unnamed_faces" default:"false"`
+	// BlurPeople IDs of people whose faces are blurred
+	BlurPeople []string `json:"blurPeople" mapstructure:"blur_people" query:"blur_person" form:"blur_person" default:"[]"`
+	// DebugOverlay annotate images with faces and selection details, only used when debug mode is enabled
+	DebugOverlay bool `json:"debugOverlay" mapstructure:"debug_overlay" query:"debug_overlay" form:"debug_overlay" default:"false"`
+	// PanoramaMode show wide panoramas at full height with a slow horizontal scroll
+	PanoramaMode bool `json:"panoramaMode" mapstructure:"panorama_mode" query:"panorama_mode" form:"panorama_mode" default:"false"`
+	// PanoramaMinRatio the aspect ratio (width / height) at which an image is treated as a panorama
+	PanoramaMinRatio float64 `json:"panoramaMinRatio" mapstructure:"panorama_min_ratio" query:"panorama_min_ratio" form:"panorama_min_ratio" default:"2.5"`
+	// PanoramaDuration how long (in seconds) a panorama scrolls for. 0 calculates it from the panorama width
+	PanoramaDuration int `json:"panoramaDuration" mapstructure:"panorama_duration" query:"panorama_duration" form:"panorama_duration" default:"0"`
+	// QualityFilter skip blurry, very dark or very bright images
+	QualityFilter bool `json:"qualityFilter" mapstructure:"quality_filter" query:"quality_filter" form:"quality_filter" default:"false"`
+	// MinSharpness the minimum sharpness score an image needs to pass the quality filter
+	MinSharpness float64 `json:"minSharpness" mapstructure:"min_sharpness" query:"min_sharpness" form:"min_sharpness" default:"30"`
+	// MinBrightness the minimum mean brightness (0-100) an image needs to pass the quality filter
+	MinBrightness float64 `json:"minBrightness" mapstructure:"min_brightness" query:"min_brightness" form:"min_brightness" default:"8"`
+	// MaxBrightness the maximum mean brightness (0-100) an image can have to pass the quality filter
+	MaxBrightness float64 `json:"maxBrightness" mapstructure:"max_brightness" query:"max_brightness" form:"max_brightness" default:"95"`
+	// MaxClipping the maximum percentage of pure black or white pixels an image can have to pass the quality filter
+	MaxClipping float64 `json:"maxClipping" mapstructure:"max_clipping" query:"max_clipping" form:"max_clipping" default:"60"`
 	// UseOriginalImage use the original image
 	UseOriginalImage bool `json:"useOriginalImage" mapstructure:"use_original_image" query:"use_original_image" form:"use_original_image" default:"false"`
 	// BackgroundBlur whether to display blurred image as background
 	BackgroundBlur bool `json:"backgroundBlur" mapstructure:"background_blur" query:"background_blur" form:"background_blur" default:"true"`
+	// BackgroundStyle how the background is built when BackgroundBlur is enabled (blur | dominant-color | gradient | matte)
+	BackgroundStyle string `json:"backgroundStyle" mapstructure:"background_style" query:"background_style" form:"background_style" default:"blur" lowercase:"true"`
+	// BackgroundBlurQuality the JPEG quality (1-100) of the blurred background, it is blurred so can be much lower than ImageQuality
+	BackgroundBlurQuality int `json:"backgroundBlurQuality" mapstructure:"background_blur_quality" query:"background_blur_quality" form:"background_blur_quality" default:"70"`
+	// ImageFormat the format images are sent to the browser in (jpeg | png)
+	ImageFormat string `json:"imageFormat" mapstructure:"image_format" query:"image_format" form:"image_format" default:"jpeg" lowercase:"true"`
+	// ImageQuality the JPEG quality (1-100) images are encoded with
+	ImageQuality int `json:"imageQuality" mapstructure:"image_quality" query:"image_quality" form:"image_quality" default:"95"`
 	// BackgroundBlur which transition to use none|fade|cross-fade
 	Transition string `json:"transition" mapstructure:"transition" query:"transition" form:"transition" default:"" lowercase:"true"`
 	// FadeTransitionDuration sets the length of the fade transition
@@ -272,12 +391,26 @@ type Config struct {
 	// UseGpu tells Kiosk to use GPU where possible
 	UseGpu bool `json:"use_gpu" mapstructure:"use_gpu" query:"use_gpu" form:"use_gpu" default:"true"`
 
+	// EinkPalette reduces raw images to an e-paper palette (mono | grey4 | acep7). Empty disables e-ink output
+	EinkPalette string `json:"einkPalette" mapstructure:"eink_palette" query:"eink_palette" form:"eink_palette" default:"" lowercase:"true"`
+	// EinkDither the error diffusion used when reducing to the e-ink palette (floyd-steinberg | atkinson | none)
+	EinkDither string `json:"einkDither" mapstructure:"eink_dither" query:"eink_dither" form:"eink_dither" default:"floyd-steinberg" lowercase:"true"`
+	// EinkFormat the e-ink output format (png | raw)
+	EinkFormat string `json:"einkFormat" mapstructure:"eink_format" query:"eink_format" form:"eink_format" default:"png" lowercase:"true"`
+
 	// Webhooks defines a list of webhook endpoints and their associated events that should trigger notifications.
 	Webhooks []Webhook `json:"webhooks" mapstructure:"webhooks" default:"[]"`
 
 	// Kiosk settings that are unable to be changed via URL queries
 	Kiosk KioskSettings `json:"kiosk" mapstructure:"kiosk"`
 
+	// Clients named client profiles, keyed by the client URL query. Each profile holds settings
+	// that override the base config for that client, before any other URL queries are applied.
+	Clients map[string]map[string]any `json:"-" mapstructure:"clients"`
+
+	// Devices binds devices, keyed by device ID, to a friendly name and client profile
+	Devices map[string]DeviceBinding `json:"-" mapstructure:"devices"`
+
 	// ClientData data sent from the client with data regarding itself
 	ClientData ClientData
 	// History past shown images
@@ -321,9 +454,16 @@ func bindEnvironmentVariables(v *viper.Viper) error {
 		{"kiosk.watch_config", "KIOSK_WATCH_CONFIG"},
 		{"kiosk.fetched_assets_size", "KIOSK_FETCHED_ASSETS_SIZE"},
 		{"kiosk.http_timeout", "KIOSK_HTTP_TIMEOUT"},
+		{"kiosk.max_image_megapixels", "KIOSK_MAX_IMAGE_MEGAPIXELS"},
+		{"kiosk.max_concurrent_decodes", "KIOSK_MAX_CONCURRENT_DECODES"},
 		{"kiosk.password", "KIOSK_PASSWORD"},
+		{"kiosk.password_hash", "KIOSK_PASSWORD_HASH"},
+		{"kiosk.admin_password", "KIOSK_ADMIN_PASSWORD"},
+		{"kiosk.admin_password_hash", "KIOSK_ADMIN_PASSWORD_HASH"},
+		{"kiosk.tokens_file", "KIOSK_TOKENS_FILE"},
 		{"kiosk.cache", "KIOSK_CACHE"},
 		{"kiosk.prefetch", "KIOSK_PREFETCH"},
+		{"kiosk.devices_file", "KIOSK_DEVICES_FILE"},
 		{"kiosk.asset_weighting", "KIOSK_ASSET_WEIGHTING"},
 		{"kiosk.debug", "KIOSK_DEBUG"},
 		{"kiosk.debug_verbose", "KIOSK_DEBUG_VERBOSE"},
@@ -405,13 +545,29 @@ func (c *Config) Load() error {
 	c.checkDebuging()
 	c.checkFetchedAssetsSize()
 	c.checkRedirects()
+	c.checkClients()
+	c.checkDevices()
+	c.checkAuth()
+	c.checkEink()
+	c.checkImageFilters()
+	c.checkBackgroundStyle()
+	c.checkQualityFilter()
+	c.checkSizeFilters()
+	c.checkPanorama()
+	c.checkImageEncoding()
+	c.checkGroup()
+	c.checkVideoWall()
 
 	return nil
 }
 
-// ConfigWithOverrides overwrites base config with ones supplied via URL queries
+// ConfigWithOverrides overwrites base config with the client's profile (if any) and then ones supplied via URL queries
 func (c *Config) ConfigWithOverrides(queries url.Values, e echo.Context) error {
 
+	if err := c.ApplyClientProfile(queries.Get("client")); err != nil {
+		return err
+	}
+
 	// check for person or album in quries and empty baseconfig slice if found
 	if queries.Has("person") || queries.Has("album") || queries.Has("date") || queries.Has("memories") {
 		c.Person = []string{}
@@ -419,12 +575,29 @@ func (c *Config) ConfigWithOverrides(queries url.Values, e echo.Context) error {
 		c.Date = []string{}
 	}
 
+	// face blurring protects privacy so URL queries can add to it but never turn it off
+	blurUnnamedFaces := c.BlurUnnamedFaces
+	blurPeople := slices.Clone(c.BlurPeople)
+
 	err := e.Bind(c)
 	if err != nil {
 		return err
 	}
 
+	c.BlurUnnamedFaces = c.BlurUnnamedFaces || blurUnnamedFaces
+	c.BlurPeople = append(blurPeople, c.BlurPeople...)
+	slices.Sort(c.BlurPeople)
+	c.BlurPeople = slices.Compact(c.BlurPeople)
+
 	c.checkExcludedAlbums()
+	c.checkImageFilters()
+	c.checkBackgroundStyle()
+	c.checkQualityFilter()
+	c.checkSizeFilters()
+	c.checkPanorama()
+	c.checkImageEncoding()
+	c.checkGroup()
+	c.checkVideoWall()
 
 	return nil
 }
@@ -0,0 +1,103 @@
+package config
+
+import (
+	"reflect"
+	"strings"
+	"sync"
+
+	"github.com/spf13/viper"
+)
+
+// clientProfileFields maps the yaml name of each setting that can be set in a client profile to its field index.
+// Only settings that can be changed via URL queries can be set per client.
+var clientProfileFields = sync.OnceValue(func() map[string]int {
+	fields := make(map[string]int)
+
+	typ := reflect.TypeOf(Config{})
+	for i := 0; i < typ.NumField(); i++ {
+		field := typ.Field(i)
+
+		name := field.Tag.Get("mapstructure")
+		if name == "" || field.Tag.Get("query") == "" {
+			continue
+		}
+
+		fields[name] = i
+	}
+
+	return fields
+})
+
+// ClientProfile returns the profile for the named client from the clients section of config.yaml.
+// Client names are matched case-insensitively.
+func (c *Config) ClientProfile(name string) (map[string]any, bool) {
+	if name == "" {
+		return nil, false
+	}
+
+	profile, ok := c.Clients[strings.ToLower(name)]
+	return profile, ok
+}
+
+// DeviceBinding returns the name and client profile the device with the given ID is bound to
+// in the devices section of config.yaml. Device IDs are matched case-insensitively.
+func (c *Config) DeviceBinding(id string) (DeviceBinding, bool) {
+	if id == "" {
+		return DeviceBinding{}, false
+	}
+
+	binding, ok := c.Devices[strings.ToLower(id)]
+	return binding, ok
+}
+
+// ApplyClientProfile layers the named client's profile onto the config.
+// Only the settings in the profile are changed, so the profile sits between
+// the base config and any URL query overrides. Unknown clients are ignored.
+func (c *Config) ApplyClientProfile(name string) error {
+	profile, ok := c.ClientProfile(name)
+	if !ok || len(profile) == 0 {
+		return nil
+	}
+
+	// like URL queries, a profile picking its own assets replaces the base config's asset buckets
+	for _, key := range []string{"person", "album", "date", "memories"} {
+		if _, ok := profile[key]; ok {
+			c.Person = []string{}
+			c.Album = []string{}
+			c.Date = []string{}
+			break
+		}
+	}
+
+	fields := clientProfileFields()
+	val := reflect.ValueOf(c).Elem()
+
+	settings := make(map[string]any, len(profile))
+
+	for key, value := range profile {
+		index, ok := fields[key]
+		if !ok {
+			continue
+		}
+
+		// slices are replaced rather than merged and must not share the base config's backing array
+		if field := val.Field(index); field.Kind() == reflect.Slice {
+			field.SetZero()
+		}
+
+		settings[key] = value
+	}
+
+	v := viper.New()
+	if err := v.MergeConfigMap(settings); err != nil {
+		return err
+	}
+
+	if err := v.Unmarshal(c); err != nil {
+		return err
+	}
+
+	c.checkLowercaseTaggedFields()
+
+	return nil
+}
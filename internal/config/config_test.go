@@ -13,6 +13,8 @@ import (
 
 	"github.com/labstack/echo/v4"
 	"github.com/stretchr/testify/assert"
+
+	"github.com/damongolding/immich-kiosk/internal/utils"
 )
 
 // TestConfigWithOverrides testing whether ImmichUrl and ImmichApiKey are immutable
@@ -311,3 +313,313 @@ func TestCheckWeatherLocations(t *testing.T) {
 		})
 	}
 }
+
+func TestCheckEink(t *testing.T) {
+	tests := []struct {
+		name            string
+		palette         string
+		dither          string
+		format          string
+		expectedPalette string
+		expectedDither  string
+		expectedFormat  string
+	}{
+		{
+			name:            "Valid values",
+			palette:         EinkPaletteACeP7,
+			dither:          EinkDitherAtkinson,
+			format:          EinkFormatRaw,
+			expectedPalette: EinkPaletteACeP7,
+			expectedDither:  EinkDitherAtkinson,
+			expectedFormat:  EinkFormatRaw,
+		},
+		{
+			name:            "Disabled",
+			palette:         "",
+			dither:          EinkDitherFloydSteinberg,
+			format:          EinkFormatPNG,
+			expectedPalette: "",
+			expectedDither:  EinkDitherFloydSteinberg,
+			expectedFormat:  EinkFormatPNG,
+		},
+		{
+			name:            "Invalid values",
+			palette:         "rainbow",
+			dither:          "ordered",
+			format:          "bmp",
+			expectedPalette: "",
+			expectedDither:  EinkDitherFloydSteinberg,
+			expectedFormat:  EinkFormatPNG,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &Config{
+				EinkPalette: tt.palette,
+				EinkDither:  tt.dither,
+				EinkFormat:  tt.format,
+			}
+
+			c.checkEink()
+
+			assert.Equal(t, tt.expectedPalette, c.EinkPalette)
+			assert.Equal(t, tt.expectedDither, c.EinkDither)
+			assert.Equal(t, tt.expectedFormat, c.EinkFormat)
+		})
+	}
+}
+
+func TestCheckImageFilters(t *testing.T) {
+	c := &Config{
+		ImageFilters: []string{"Sepia", "", "unknown", " vignette ", "none"},
+	}
+
+	c.checkImageFilters()
+
+	assert.Equal(t, []string{ImageFilterSepia, ImageFilterVignette}, c.ImageFilters)
+}
+
+// TestImageFiltersQuery tests that image filters use the same key in URL queries as in the config file
+func TestImageFiltersQuery(t *testing.T) {
+	c := New()
+
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodGet, "/?image_filters=sepia&image_filters=vignette", nil)
+	rec := httptest.NewRecorder()
+	echoContenx := e.NewContext(req, rec)
+
+	err := c.ConfigWithOverrides(echoContenx.QueryParams(), echoContenx)
+	assert.NoError(t, err, "ConfigWithOverrides should not return an error")
+
+	assert.Equal(t, []string{ImageFilterSepia, ImageFilterVignette}, c.ImageFilters)
+}
+
+func TestCheckBackgroundStyle(t *testing.T) {
+	tests := []struct {
+		name     string
+		style    string
+		expected string
+	}{
+		{name: "Blur", style: BackgroundStyleBlur, expected: BackgroundStyleBlur},
+		{name: "Dominant colour", style: BackgroundStyleDominantColor, expected: BackgroundStyleDominantColor},
+		{name: "Mixed case", style: "Matte", expected: BackgroundStyleMatte},
+		{name: "Empty", style: "", expected: BackgroundStyleBlur},
+		{name: "Invalid", style: "rainbow", expected: BackgroundStyleBlur},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &Config{BackgroundStyle: tt.style}
+			c.checkBackgroundStyle()
+			assert.Equal(t, tt.expected, c.BackgroundStyle)
+		})
+	}
+}
+
+func TestCheckQualityFilter(t *testing.T) {
+	c := &Config{
+		MinSharpness:  -5,
+		MinBrightness: 90,
+		MaxBrightness: 150,
+		MaxClipping:   -1,
+	}
+
+	c.checkQualityFilter()
+
+	assert.Equal(t, 0.0, c.MinSharpness)
+	assert.Equal(t, 90.0, c.MinBrightness)
+	assert.Equal(t, 100.0, c.MaxBrightness)
+	assert.Equal(t, 0.0, c.MaxClipping)
+
+	c = &Config{MinBrightness: 80, MaxBrightness: 20}
+	c.checkQualityFilter()
+
+	assert.Equal(t, 20.0, c.MinBrightness, "min and max brightness should be swapped")
+	assert.Equal(t, 80.0, c.MaxBrightness, "min and max brightness should be swapped")
+}
+
+func TestCheckSizeFilters(t *testing.T) {
+	c := &Config{
+		MinWidth:       -1,
+		MinHeight:      600,
+		MinMegapixels:  -2,
+		MinAspectRatio: 3,
+		MaxAspectRatio: 0.5,
+	}
+
+	c.checkSizeFilters()
+
+	assert.Equal(t, 0, c.MinWidth)
+	assert.Equal(t, 600, c.MinHeight)
+	assert.Equal(t, 0.0, c.MinMegapixels)
+	assert.Equal(t, 0.5, c.MinAspectRatio, "aspect ratio bounds should be swapped")
+	assert.Equal(t, 3.0, c.MaxAspectRatio, "aspect ratio bounds should be swapped")
+}
+
+func TestCheckPanorama(t *testing.T) {
+	c := &Config{PanoramaMinRatio: 0.5, PanoramaDuration: -10}
+
+	c.checkPanorama()
+
+	assert.Equal(t, defaultPanoramaMinRatio, c.PanoramaMinRatio)
+	assert.Equal(t, 0, c.PanoramaDuration)
+}
+
+func TestCheckImageEncoding(t *testing.T) {
+	tests := []struct {
+		name                string
+		format              string
+		quality             int
+		blurQuality         int
+		expectedFormat      string
+		expectedQuality     int
+		expectedBlurQuality int
+	}{
+		{"Valid", "PNG", 80, 50, utils.ImageFormatPNG, 80, 50},
+		{"Jpg alias", "jpg", 95, 70, utils.ImageFormatJPEG, 95, 70},
+		{"Invalid format", "webp", 95, 70, utils.ImageFormatJPEG, 95, 70},
+		{"Out of range qualities", "jpeg", 0, 101, utils.ImageFormatJPEG, utils.DefaultJPEGQuality, defaultBackgroundBlurQuality},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &Config{ImageFormat: tt.format, ImageQuality: tt.quality, BackgroundBlurQuality: tt.blurQuality}
+
+			c.checkImageEncoding()
+
+			assert.Equal(t, tt.expectedFormat, c.ImageFormat)
+			assert.Equal(t, tt.expectedQuality, c.ImageQuality)
+			assert.Equal(t, tt.expectedBlurQuality, c.BackgroundBlurQuality)
+		})
+	}
+}
+
+// TestBlurFacesOverrides tests that URL queries can add to face blurring but not turn it off
+func TestBlurFacesOverrides(t *testing.T) {
+	c := New()
+	c.BlurUnnamedFaces = true
+	c.BlurPeople = []string{"person-a"}
+
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodGet, "/?blur_unnamed_faces=false&blur_person=person-b", nil)
+	rec := httptest.NewRecorder()
+	echoContenx := e.NewContext(req, rec)
+
+	err := c.ConfigWithOverrides(echoContenx.QueryParams(), echoContenx)
+	assert.NoError(t, err, "ConfigWithOverrides should not return an error")
+
+	assert.True(t, c.BlurUnnamedFaces, "blur_unnamed_faces was allowed to be turned off")
+	assert.Equal(t, []string{"person-a", "person-b"}, c.BlurPeople)
+}
+
+// TestClientProfiles tests that client profiles are layered between the base config and URL queries
+func TestClientProfiles(t *testing.T) {
+	base := New()
+	base.ImmichUrl = "https://my-server.com"
+	base.Album = []string{"base-album-1", "base-album-2"}
+	base.Person = []string{"base-person"}
+	base.Refresh = 60
+	base.Clients = map[string]map[string]any{
+		"kitchen": {
+			"show_time":  true,
+			"album":      []any{"kitchen-album"},
+			"refresh":    10,
+			"layout":     "SPLITVIEW",
+			"immich_url": "https://other-server.com",
+		},
+	}
+
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodGet, "/?client=Kitchen&refresh=30", nil)
+	rec := httptest.NewRecorder()
+	echoContenx := e.NewContext(req, rec)
+
+	c := *base
+	err := c.ConfigWithOverrides(echoContenx.QueryParams(), echoContenx)
+	assert.NoError(t, err, "ConfigWithOverrides should not return an error")
+
+	assert.True(t, c.ShowTime, "Profile settings should be applied")
+	assert.Equal(t, "splitview", c.Layout, "Profile settings should be lowercased")
+	assert.Equal(t, []string{"kitchen-album"}, c.Album, "Profile albums should replace the base albums")
+	assert.Empty(t, c.Person, "Profile albums should replace the base people")
+	assert.Equal(t, 30, c.Refresh, "URL queries should override the profile")
+	assert.Equal(t, "https://my-server.com", c.ImmichUrl, "Settings that can't be set via URL should be ignored")
+
+	assert.Equal(t, []string{"base-album-1", "base-album-2"}, base.Album, "Base config should not be changed")
+
+	req = httptest.NewRequest(http.MethodGet, "/?client=unknown", nil)
+	echoContenx = e.NewContext(req, httptest.NewRecorder())
+
+	c = *base
+	err = c.ConfigWithOverrides(echoContenx.QueryParams(), echoContenx)
+	assert.NoError(t, err, "ConfigWithOverrides should not return an error")
+	assert.Equal(t, base.Album, c.Album, "Unknown clients should use the base config")
+	assert.False(t, c.ShowTime)
+}
+
+func TestCheckGroup(t *testing.T) {
+	tests := []struct {
+		name     string
+		mode     string
+		expected string
+	}{
+		{name: "Same", mode: GroupModeSame, expected: GroupModeSame},
+		{name: "Mixed case", mode: "Different", expected: GroupModeDifferent},
+		{name: "Empty", mode: "", expected: GroupModeSame},
+		{name: "Invalid", mode: "mirror", expected: GroupModeSame},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &Config{Group: " living-room ", GroupMode: tt.mode}
+			c.checkGroup()
+			assert.Equal(t, "living-room", c.Group)
+			assert.Equal(t, tt.expected, c.GroupMode)
+		})
+	}
+}
+
+func TestCheckVideoWall(t *testing.T) {
+	c := &Config{WallColumns: 2, WallRows: 2, WallColumn: 3, WallRow: 0, WallBezel: -10}
+	c.checkVideoWall()
+
+	assert.Equal(t, 1, c.WallColumn)
+	assert.Equal(t, 1, c.WallRow)
+	assert.Equal(t, 0, c.WallBezel)
+
+	c = &Config{WallColumns: -2, WallRows: 2, WallColumn: 2, WallRow: 2}
+	c.checkVideoWall()
+
+	assert.Equal(t, 0, c.WallColumns, "an invalid wall size should disable the video wall")
+	assert.Equal(t, 0, c.WallRows, "an invalid wall size should disable the video wall")
+}
+
+// TestCheckAuth tests password hashes replace plain text passwords
+func TestCheckAuth(t *testing.T) {
+	c := &Config{}
+	c.Kiosk.Password = "1234"
+	c.Kiosk.PasswordHash = " $2a$10$7EqJtq98hPqEX7fNZaFWoOa3r4bWkTq9yQ6Oq7pX8mJzV0q1Ywq2e "
+	c.Kiosk.AdminPassword = "admin"
+	c.checkAuth()
+
+	assert.Empty(t, c.Kiosk.Password, "the hash should replace the plain text password")
+	assert.Equal(t, "$2a$10$7EqJtq98hPqEX7fNZaFWoOa3r4bWkTq9yQ6Oq7pX8mJzV0q1Ywq2e", c.Kiosk.PasswordHash)
+	assert.Equal(t, "admin", c.Kiosk.AdminPassword, "passwords without a hash should be kept")
+
+	c = &Config{}
+	c.Kiosk.AdminPassword = "admin"
+	c.Kiosk.AdminPasswordHash = "not-a-hash"
+	c.checkAuth()
+
+	assert.Equal(t, "not-a-hash", c.Kiosk.AdminPasswordHash, "invalid hashes should be kept so logging in fails")
+	assert.Empty(t, c.Kiosk.AdminPassword)
+
+	c = &Config{}
+	c.Kiosk.Password = "1234"
+	c.Kiosk.AdminPassword = "1234"
+	c.checkAuth()
+
+	assert.Equal(t, "1234", c.Kiosk.Password)
+	assert.Empty(t, c.Kiosk.AdminPassword, "the admin password should be different to the password")
+}
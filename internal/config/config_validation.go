@@ -5,9 +5,14 @@ import (
 	"net/url"
 	"os"
 	"reflect"
+	"slices"
 	"strings"
 
 	"github.com/charmbracelet/log"
+	"golang.org/x/crypto/bcrypt"
+
+	"github.com/damongolding/immich-kiosk/internal/auth"
+	"github.com/damongolding/immich-kiosk/internal/utils"
 )
 
 // validateConfigFile checks if the given file path is valid and not a directory.
@@ -323,3 +328,268 @@ func (c *Config) checkAlbumOrder() {
 		c.AlbumOrder = AlbumOrderRandom
 	}
 }
+
+// checkEink validates the e-ink palette, dither and format values.
+// An invalid palette disables e-ink output, while an invalid dither or format
+// is reset to its default. A warning is logged for each invalid value.
+func (c *Config) checkEink() {
+	switch c.EinkPalette {
+	case "", EinkPaletteMono, EinkPaletteGrey4, EinkPaletteACeP7:
+	default:
+		log.Warnf("Invalid eink_palette value: %s. Disabling e-ink output", c.EinkPalette)
+		c.EinkPalette = ""
+	}
+
+	switch c.EinkDither {
+	case EinkDitherFloydSteinberg, EinkDitherAtkinson, EinkDitherNone:
+	default:
+		log.Warnf("Invalid eink_dither value: %s. Using default: %s", c.EinkDither, EinkDitherFloydSteinberg)
+		c.EinkDither = EinkDitherFloydSteinberg
+	}
+
+	switch c.EinkFormat {
+	case EinkFormatPNG, EinkFormatRaw:
+	default:
+		log.Warnf("Invalid eink_format value: %s. Using default: %s", c.EinkFormat, EinkFormatPNG)
+		c.EinkFormat = EinkFormatPNG
+	}
+}
+
+// checkImageFilters lowercases the configured image filters and removes
+// empty or unknown filters, logging a warning for each unknown filter.
+func (c *Config) checkImageFilters() {
+	if len(c.ImageFilters) == 0 {
+		return
+	}
+
+	validFilters := []string{
+		ImageFilterBlackAndWhite,
+		ImageFilterSepia,
+		ImageFilterVintage,
+		ImageFilterVignette,
+		ImageFilterAutoContrast,
+		ImageFilterWarm,
+	}
+
+	filters := []string{}
+	for _, filter := range c.ImageFilters {
+		filter = strings.ToLower(strings.TrimSpace(filter))
+		if filter == "" || filter == "none" {
+			continue
+		}
+		if !slices.Contains(validFilters, filter) {
+			log.Warn("Ignoring unknown image filter", "filter", filter)
+			continue
+		}
+		filters = append(filters, filter)
+	}
+
+	c.ImageFilters = filters
+}
+
+// checkBackgroundStyle validates the background style, falling back to blur for unknown values.
+func (c *Config) checkBackgroundStyle() {
+	c.BackgroundStyle = strings.ToLower(strings.TrimSpace(c.BackgroundStyle))
+
+	switch c.BackgroundStyle {
+	case BackgroundStyleBlur, BackgroundStyleDominantColor, BackgroundStyleGradient, BackgroundStyleMatte:
+	case "":
+		c.BackgroundStyle = BackgroundStyleBlur
+	default:
+		log.Warnf("Invalid background_style value: %s. Using default: %s", c.BackgroundStyle, BackgroundStyleBlur)
+		c.BackgroundStyle = BackgroundStyleBlur
+	}
+}
+
+// checkQualityFilter keeps the quality filter thresholds within their valid ranges.
+func (c *Config) checkQualityFilter() {
+	if c.MinSharpness < 0 {
+		log.Warnf("Invalid min_sharpness value: %v. Using 0", c.MinSharpness)
+		c.MinSharpness = 0
+	}
+
+	c.MinBrightness = max(0, min(100, c.MinBrightness))
+	c.MaxBrightness = max(0, min(100, c.MaxBrightness))
+	c.MaxClipping = max(0, min(100, c.MaxClipping))
+
+	if c.MinBrightness > c.MaxBrightness {
+		log.Warnf("min_brightness (%v) is greater than max_brightness (%v). Swapping values", c.MinBrightness, c.MaxBrightness)
+		c.MinBrightness, c.MaxBrightness = c.MaxBrightness, c.MinBrightness
+	}
+}
+
+// checkSizeFilters disables negative size filters and swaps the aspect ratio bounds if they are reversed.
+func (c *Config) checkSizeFilters() {
+	if c.MinWidth < 0 {
+		log.Warnf("Invalid min_width value: %d. Disabling", c.MinWidth)
+		c.MinWidth = 0
+	}
+
+	if c.MinHeight < 0 {
+		log.Warnf("Invalid min_height value: %d. Disabling", c.MinHeight)
+		c.MinHeight = 0
+	}
+
+	if c.MinMegapixels < 0 {
+		log.Warnf("Invalid min_megapixels value: %v. Disabling", c.MinMegapixels)
+		c.MinMegapixels = 0
+	}
+
+	if c.MinAspectRatio < 0 {
+		log.Warnf("Invalid min_aspect_ratio value: %v. Disabling", c.MinAspectRatio)
+		c.MinAspectRatio = 0
+	}
+
+	if c.MaxAspectRatio < 0 {
+		log.Warnf("Invalid max_aspect_ratio value: %v. Disabling", c.MaxAspectRatio)
+		c.MaxAspectRatio = 0
+	}
+
+	if c.MinAspectRatio > 0 && c.MaxAspectRatio > 0 && c.MinAspectRatio > c.MaxAspectRatio {
+		log.Warnf("min_aspect_ratio (%v) is greater than max_aspect_ratio (%v). Swapping values", c.MinAspectRatio, c.MaxAspectRatio)
+		c.MinAspectRatio, c.MaxAspectRatio = c.MaxAspectRatio, c.MinAspectRatio
+	}
+}
+
+// checkPanorama ensures the panorama ratio is wider than square and the duration is not negative.
+func (c *Config) checkPanorama() {
+	if c.PanoramaMinRatio <= 1 {
+		log.Warnf("Invalid panorama_min_ratio value: %v. Using default: %v", c.PanoramaMinRatio, defaultPanoramaMinRatio)
+		c.PanoramaMinRatio = defaultPanoramaMinRatio
+	}
+
+	if c.PanoramaDuration < 0 {
+		log.Warnf("Invalid panorama_duration value: %d. Using 0 (automatic)", c.PanoramaDuration)
+		c.PanoramaDuration = 0
+	}
+}
+
+// checkImageEncoding validates the output image format and keeps the JPEG qualities within 1-100.
+func (c *Config) checkImageEncoding() {
+	c.ImageFormat = strings.ToLower(strings.TrimSpace(c.ImageFormat))
+
+	switch c.ImageFormat {
+	case utils.ImageFormatJPEG, utils.ImageFormatPNG:
+	case "", "jpg":
+		c.ImageFormat = utils.ImageFormatJPEG
+	default:
+		log.Warnf("Invalid image_format value: %s. Using default: %s", c.ImageFormat, utils.ImageFormatJPEG)
+		c.ImageFormat = utils.ImageFormatJPEG
+	}
+
+	if c.ImageQuality < 1 || c.ImageQuality > 100 {
+		log.Warnf("Invalid image_quality value: %d. Using default: %d", c.ImageQuality, utils.DefaultJPEGQuality)
+		c.ImageQuality = utils.DefaultJPEGQuality
+	}
+
+	if c.BackgroundBlurQuality < 1 || c.BackgroundBlurQuality > 100 {
+		log.Warnf("Invalid background_blur_quality value: %d. Using default: %d", c.BackgroundBlurQuality, defaultBackgroundBlurQuality)
+		c.BackgroundBlurQuality = defaultBackgroundBlurQuality
+	}
+}
+
+// checkGroup validates the group mode, falling back to same for unknown values.
+func (c *Config) checkGroup() {
+	c.Group = strings.TrimSpace(c.Group)
+	c.GroupMode = strings.ToLower(strings.TrimSpace(c.GroupMode))
+
+	switch c.GroupMode {
+	case GroupModeSame, GroupModeDifferent:
+	case "":
+		c.GroupMode = GroupModeSame
+	default:
+		log.Warnf("Invalid group_mode value: %s. Using default: %s", c.GroupMode, GroupModeSame)
+		c.GroupMode = GroupModeSame
+	}
+}
+
+// checkVideoWall keeps the video wall size positive and the device's position inside the wall.
+func (c *Config) checkVideoWall() {
+	if c.WallColumns < 0 || c.WallRows < 0 {
+		log.Warnf("Invalid video wall size: %dx%d. Disabling the video wall", c.WallColumns, c.WallRows)
+		c.WallColumns, c.WallRows = 0, 0
+	}
+
+	if c.WallColumns == 0 || c.WallRows == 0 {
+		return
+	}
+
+	if c.WallColumn < 1 || c.WallColumn > c.WallColumns {
+		log.Warnf("Invalid wall_column value: %d. Using 1", c.WallColumn)
+		c.WallColumn = 1
+	}
+
+	if c.WallRow < 1 || c.WallRow > c.WallRows {
+		log.Warnf("Invalid wall_row value: %d. Using 1", c.WallRow)
+		c.WallRow = 1
+	}
+
+	if c.WallBezel < 0 {
+		log.Warnf("Invalid wall_bezel value: %d. Using 0", c.WallBezel)
+		c.WallBezel = 0
+	}
+}
+
+// checkClients warns about client profile settings that can not be set per client.
+// Such settings are ignored when the profile is applied.
+func (c *Config) checkClients() {
+	fields := clientProfileFields()
+
+	for name, profile := range c.Clients {
+		for key := range profile {
+			if _, ok := fields[key]; !ok {
+				log.Warnf("Client %s: %s can not be set per client and will be ignored", name, key)
+			}
+		}
+	}
+}
+
+// checkDevices warns about devices bound to a client profile that does not exist.
+func (c *Config) checkDevices() {
+	for id, binding := range c.Devices {
+		if binding.Client == "" {
+			continue
+		}
+		if _, ok := c.ClientProfile(binding.Client); !ok {
+			log.Warn("Device bound to unknown client", "device", id, "client", binding.Client)
+		}
+	}
+}
+
+// checkAuth checks password hashes are bcrypt hashes and drops plain text passwords that a hash replaces.
+// Invalid hashes are kept so logging in fails rather than Kiosk being left unprotected.
+// An admin password that is the same as the Kiosk password is dropped.
+func (c *Config) checkAuth() {
+	passwords := []struct {
+		name, hashName string
+		password, hash *string
+	}{
+		{"password", "password_hash", &c.Kiosk.Password, &c.Kiosk.PasswordHash},
+		{"admin_password", "admin_password_hash", &c.Kiosk.AdminPassword, &c.Kiosk.AdminPasswordHash},
+	}
+
+	for _, p := range passwords {
+		*p.hash = strings.TrimSpace(*p.hash)
+		if *p.hash == "" {
+			continue
+		}
+
+		if _, err := bcrypt.Cost([]byte(*p.hash)); err != nil {
+			log.Errorf("%s is not a bcrypt hash, logging in will fail", p.hashName)
+		}
+
+		if *p.password != "" {
+			log.Warnf("Both %s and %s are set. Using %s", p.name, p.hashName, p.hashName)
+			*p.password = ""
+		}
+	}
+
+	// the Kiosk password is sent in URLs by devices, so it can not also be the admin password
+	admin := auth.Credential{Secret: c.Kiosk.AdminPassword, Hash: c.Kiosk.AdminPasswordHash}
+	kiosk := auth.Credential{Secret: c.Kiosk.Password, Hash: c.Kiosk.PasswordHash}
+	if admin.Matches(kiosk) {
+		log.Error("admin_password must be different to password. The admin dashboard and remote control are disabled")
+		c.Kiosk.AdminPassword = ""
+		c.Kiosk.AdminPasswordHash = ""
+	}
+}
@@ -0,0 +1,355 @@
+// Package devices keeps a registry of the devices (browsers and frames) displaying Kiosk.
+//
+// Each device is identified by a stable ID stored in a cookie on the device. The registry records
+// when and from where each device was last seen, what it is showing and its screen size,
+// and is persisted to a JSON file so it survives restarts. Only IDs Kiosk issued, or that are
+// bound in config.yaml, are added, and the registry is capped at maxDevices.
+package devices
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/charmbracelet/log"
+)
+
+const (
+	// maxIDLength is the longest device ID accepted from a client
+	maxIDLength = 64
+	// staleAfter is how long a device can go unseen before it is removed from the registry
+	staleAfter = 90 * 24 * time.Hour
+	// unconfirmedAfter is how long a device that never came back after its first visit is kept
+	unconfirmedAfter = time.Hour
+	// maxDevices is the most devices kept in the registry, the least recently seen are removed first
+	maxDevices = 1000
+	// onlineWindow is how recently a device must have been seen to count as online.
+	// Open pages check in with the server every few seconds.
+	onlineWindow = 30 * time.Second
+)
+
+var (
+	mu        sync.RWMutex
+	registry  = make(map[string]*Device)
+	storePath string
+	dirty     bool
+
+	// pending holds the commands waiting for each device and queued the asset each device should show next.
+	// Neither is persisted.
+	pending      = make(map[string][]Command)
+	queuedAssets = make(map[string]string)
+)
+
+// Device is a single device known to the registry
+type Device struct {
+	// ID the stable identifier stored on the device
+	ID string `json:"id"`
+	// Name the friendly name given to the device in config.yaml
+	Name string `json:"name"`
+	// Client the client profile the device last used, from its URL or its binding in config.yaml
+	Client string `json:"client"`
+	// IP the address the device was last seen from
+	IP string `json:"ip"`
+	// UserAgent the user agent the device last sent
+	UserAgent string `json:"userAgent"`
+	// Width and Height the viewport size of the device in CSS pixels
+	Width  int `json:"width"`
+	Height int `json:"height"`
+	// DevicePixelRatio the ratio of physical pixels to CSS pixels on the device
+	DevicePixelRatio float64 `json:"devicePixelRatio"`
+	// CurrentAssets the IDs of the assets the device is currently showing
+	CurrentAssets []string `json:"currentAssets"`
+	// Sleeping is true while the device has been put to sleep remotely
+	Sleeping bool `json:"sleeping"`
+	// FirstSeen and LastSeen when the device first and most recently made a request
+	FirstSeen time.Time `json:"firstSeen"`
+	LastSeen  time.Time `json:"lastSeen"`
+}
+
+// Online reports whether the device has been seen recently enough to count as connected.
+func (d Device) Online(now time.Time) bool {
+	return now.Sub(d.LastSeen) <= onlineWindow
+}
+
+// Visit holds what is known about a device from a single request.
+// Zero values (other than Name) are ignored so requests that do not send them keep the last known values.
+type Visit struct {
+	Name             string
+	Client           string
+	IP               string
+	UserAgent        string
+	Width            int
+	Height           int
+	DevicePixelRatio float64
+}
+
+// ValidID reports whether id can be used as a device ID.
+// IDs are generated by Kiosk but sent back by the device, so they are limited to
+// a reasonable length of letters, digits, dashes and underscores.
+func ValidID(id string) bool {
+	if id == "" || len(id) > maxIDLength {
+		return false
+	}
+
+	for _, r := range id {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '-', r == '_':
+		default:
+			return false
+		}
+	}
+
+	return true
+}
+
+// Load reads the registry from the JSON file at path and uses path for future saves.
+// A missing file is not an error, the registry simply starts empty.
+func Load(path string) error {
+	mu.Lock()
+	defer mu.Unlock()
+
+	storePath = path
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+		return err
+	}
+
+	var stored []Device
+	if err := json.Unmarshal(data, &stored); err != nil {
+		return err
+	}
+
+	registry = make(map[string]*Device, len(stored))
+	for _, device := range stored {
+		if !ValidID(device.ID) {
+			continue
+		}
+		registry[device.ID] = &device
+	}
+
+	return nil
+}
+
+// Save writes the registry to its JSON file, removing devices that have not been seen for a long time.
+// The file is written to a temporary file first and renamed so a crash never leaves a partial file.
+func Save() error {
+	mu.Lock()
+	defer mu.Unlock()
+
+	if storePath == "" || !dirty {
+		return nil
+	}
+
+	prune(time.Now())
+
+	data, err := json.MarshalIndent(sortedDevices(), "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(storePath), 0o755); err != nil {
+		return err
+	}
+
+	tmp := storePath + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		return err
+	}
+
+	if err := os.Rename(tmp, storePath); err != nil {
+		return err
+	}
+
+	dirty = false
+
+	return nil
+}
+
+// Persist saves the registry every interval until the context is cancelled.
+func Persist(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := Save(); err != nil {
+				log.Error("saving devices", "path", storePath, "err", err)
+			}
+		}
+	}
+}
+
+// prune removes devices not seen for staleAfter, and devices that never came back after their
+// first visit (e.g. a browser without cookies) once they have not been seen for unconfirmedAfter.
+// The caller must hold the lock.
+func prune(now time.Time) {
+	for id, device := range registry {
+		unconfirmed := device.LastSeen.Sub(device.FirstSeen) < onlineWindow
+
+		if now.Sub(device.LastSeen) > staleAfter || (unconfirmed && now.Sub(device.LastSeen) > unconfirmedAfter) {
+			remove(id)
+		}
+	}
+}
+
+// remove deletes the device with the given ID and anything waiting for it.
+// The caller must hold the lock.
+func remove(id string) {
+	delete(registry, id)
+	delete(pending, id)
+	delete(queuedAssets, id)
+	dirty = true
+}
+
+// makeRoom removes devices until there is room for a new one, pruning first and then
+// removing the least recently seen devices. The caller must hold the lock.
+func makeRoom(now time.Time) {
+	if len(registry) < maxDevices {
+		return
+	}
+
+	prune(now)
+
+	for len(registry) >= maxDevices {
+		oldest := ""
+		for id, device := range registry {
+			if oldest == "" || device.LastSeen.Before(registry[oldest].LastSeen) {
+				oldest = id
+			}
+		}
+		remove(oldest)
+	}
+}
+
+// Known reports whether the device with the given ID is in the registry.
+func Known(id string) bool {
+	mu.RLock()
+	defer mu.RUnlock()
+
+	_, ok := registry[id]
+	return ok
+}
+
+// Seen records a request from the device with the given ID, adding it to the registry if it is new.
+// Only IDs Kiosk issued or that are bound in config.yaml should be added, see Known.
+// It returns a copy of the updated device.
+func Seen(id string, visit Visit) (Device, bool) {
+	if !ValidID(id) {
+		return Device{}, false
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	now := time.Now()
+
+	device, ok := registry[id]
+	if !ok {
+		makeRoom(now)
+		device = &Device{ID: id, FirstSeen: now}
+		registry[id] = device
+		log.Info("New device", "id", id, "ip", visit.IP)
+	}
+
+	device.Name = visit.Name
+	device.LastSeen = now
+
+	if visit.Client != "" {
+		device.Client = visit.Client
+	}
+
+	if visit.IP != "" {
+		device.IP = visit.IP
+	}
+
+	if visit.UserAgent != "" {
+		device.UserAgent = visit.UserAgent
+	}
+
+	if visit.Width > 0 && visit.Height > 0 {
+		device.Width = visit.Width
+		device.Height = visit.Height
+	}
+
+	if visit.DevicePixelRatio > 0 {
+		device.DevicePixelRatio = visit.DevicePixelRatio
+	}
+
+	dirty = true
+
+	return cloneDevice(device), true
+}
+
+// SetCurrentAssets records the assets the device with the given ID is now showing.
+// Unknown devices are ignored.
+func SetCurrentAssets(id string, assetIDs []string) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	device, ok := registry[id]
+	if !ok {
+		return
+	}
+
+	device.CurrentAssets = slices.Clone(assetIDs)
+	dirty = true
+}
+
+// Get returns a copy of the device with the given ID.
+func Get(id string) (Device, bool) {
+	mu.RLock()
+	defer mu.RUnlock()
+
+	device, ok := registry[id]
+	if !ok {
+		return Device{}, false
+	}
+
+	return cloneDevice(device), true
+}
+
+// All returns a copy of every known device, most recently seen first.
+func All() []Device {
+	mu.RLock()
+	defer mu.RUnlock()
+
+	return sortedDevices()
+}
+
+// sortedDevices returns copies of all devices, most recently seen first.
+// The caller must hold the lock.
+func sortedDevices() []Device {
+	all := make([]Device, 0, len(registry))
+	for _, device := range registry {
+		all = append(all, cloneDevice(device))
+	}
+
+	slices.SortFunc(all, func(a, b Device) int {
+		if c := b.LastSeen.Compare(a.LastSeen); c != 0 {
+			return c
+		}
+		return strings.Compare(a.ID, b.ID)
+	})
+
+	return all
+}
+
+// cloneDevice copies a device so callers can not modify the registry.
+func cloneDevice(device *Device) Device {
+	clone := *device
+	clone.CurrentAssets = slices.Clone(device.CurrentAssets)
+	return clone
+}
@@ -0,0 +1,138 @@
+package devices
+
+import (
+	"errors"
+	"slices"
+	"strings"
+)
+
+// Action is what a command asks a device to do
+type Action string
+
+const (
+	ActionNext      Action = "next"
+	ActionPrevious  Action = "previous"
+	ActionPause     Action = "pause"
+	ActionResume    Action = "resume"
+	ActionSleep     Action = "sleep"
+	ActionWake      Action = "wake"
+	ActionShowAsset Action = "show_asset"
+	ActionReload    Action = "reload"
+)
+
+// Actions lists every action a device understands
+var Actions = []Action{
+	ActionNext,
+	ActionPrevious,
+	ActionPause,
+	ActionResume,
+	ActionSleep,
+	ActionWake,
+	ActionShowAsset,
+	ActionReload,
+}
+
+// Command is an instruction sent to a device
+type Command struct {
+	// Action what the device should do
+	Action Action `json:"action"`
+	// AssetID the asset to show, only used by the show_asset action
+	AssetID string `json:"assetID,omitempty"`
+}
+
+// Validate checks the command has a known action and, for show_asset, an asset to show.
+func (c Command) Validate() error {
+	if !slices.Contains(Actions, c.Action) {
+		return errors.New("unknown action " + string(c.Action))
+	}
+
+	if c.Action == ActionShowAsset && c.AssetID == "" {
+		return errors.New("show_asset needs an assetID")
+	}
+
+	return nil
+}
+
+// SendCommand queues a command for the device with the given ID, to be pushed straight away
+// to every push connection it has or delivered the next time it checks in.
+// Sleep and wake also change the device's sleep state and show_asset queues the asset for the device's next image,
+// so the server stays in step with the device. It returns false if the device is unknown.
+// A command repeated straight after itself is only queued once.
+func SendCommand(id string, command Command) bool {
+	mu.Lock()
+	defer mu.Unlock()
+
+	device, ok := registry[id]
+	if !ok {
+		return false
+	}
+
+	switch command.Action {
+	case ActionSleep, ActionWake:
+		device.Sleeping = command.Action == ActionSleep
+		dirty = true
+	case ActionShowAsset:
+		queuedAssets[id] = command.AssetID
+	}
+
+	if !push(id, command) {
+		pending[id] = queueCommand(pending[id], command)
+	}
+
+	return true
+}
+
+// queueCommand appends a command to a queue unless it repeats the last queued command.
+// Earlier copies are kept so the device runs the commands in the order they were sent.
+func queueCommand(queue []Command, command Command) []Command {
+	if len(queue) > 0 && queue[len(queue)-1] == command {
+		return queue
+	}
+
+	return append(queue, command)
+}
+
+// SendClientCommand queues a command for every device that last used the named client profile.
+// Client names are matched case-insensitively. It returns the IDs of the devices the command was sent to.
+func SendClientCommand(client string, command Command) []string {
+	var ids []string
+
+	for _, device := range All() {
+		if device.Client != "" && strings.EqualFold(device.Client, client) && SendCommand(device.ID, command) {
+			ids = append(ids, device.ID)
+		}
+	}
+
+	return ids
+}
+
+// TakeCommands returns and clears the commands waiting for the device with the given ID.
+func TakeCommands(id string) []Command {
+	mu.Lock()
+	defer mu.Unlock()
+
+	commands := pending[id]
+	delete(pending, id)
+
+	return commands
+}
+
+// TakeQueuedAsset returns and clears the asset the device with the given ID was asked to show next.
+func TakeQueuedAsset(id string) (string, bool) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	assetID, ok := queuedAssets[id]
+	delete(queuedAssets, id)
+
+	return assetID, ok
+}
+
+// IsSleeping reports whether the device with the given ID has been put to sleep remotely.
+func IsSleeping(id string) bool {
+	mu.RLock()
+	defer mu.RUnlock()
+
+	device, ok := registry[id]
+	return ok && device.Sleeping
+}
@@ -0,0 +1,93 @@
+package devices
+
+// Subscription is a single push connection to a device.
+// A device can have more than one connection, e.g. the same browser in two tabs,
+// and every connection receives every command sent to the device.
+type Subscription struct {
+	id       string
+	wake     chan struct{}
+	commands []Command
+}
+
+// subscribers holds the open push connections, by device ID.
+var subscribers = make(map[string]map[*Subscription]struct{})
+
+// Subscribe opens a push connection for the device with the given ID.
+// Commands already waiting for the device are handed to the new connection.
+func Subscribe(id string) *Subscription {
+	mu.Lock()
+	defer mu.Unlock()
+
+	s := &Subscription{
+		id:   id,
+		wake: make(chan struct{}, 1),
+	}
+
+	if subscribers[id] == nil {
+		subscribers[id] = make(map[*Subscription]struct{})
+	}
+	subscribers[id][s] = struct{}{}
+
+	// wake the connection straight away if commands are already waiting
+	if len(pending[id]) > 0 {
+		s.commands = pending[id]
+		delete(pending, id)
+		s.wake <- struct{}{}
+	}
+
+	return s
+}
+
+// Wake receives a value whenever commands are queued for the connection.
+func (s *Subscription) Wake() <-chan struct{} {
+	return s.wake
+}
+
+// TakeCommands returns and clears the commands waiting for the connection.
+func (s *Subscription) TakeCommands() []Command {
+	mu.Lock()
+	defer mu.Unlock()
+
+	commands := s.commands
+	s.commands = nil
+
+	return commands
+}
+
+// Close closes the push connection.
+func (s *Subscription) Close() {
+	mu.Lock()
+	defer mu.Unlock()
+
+	delete(subscribers[s.id], s)
+	if len(subscribers[s.id]) == 0 {
+		delete(subscribers, s.id)
+	}
+}
+
+// Connected reports whether the device with the given ID has an open push connection.
+func Connected(id string) bool {
+	mu.RLock()
+	defer mu.RUnlock()
+
+	return len(subscribers[id]) > 0
+}
+
+// push queues a command on every push connection of the device with the given ID and wakes them without blocking.
+// It returns false if the device has no push connection. The caller must hold the lock.
+func push(id string, command Command) bool {
+	if len(subscribers[id]) == 0 {
+		return false
+	}
+
+	for s := range subscribers[id] {
+		s.commands = queueCommand(s.commands, command)
+
+		select {
+		case s.wake <- struct{}{}:
+		default:
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,269 @@
+package devices
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// resetRegistry empties the registry and points it at a file in a temporary directory
+func resetRegistry(t *testing.T) string {
+	t.Helper()
+
+	mu.Lock()
+	registry = make(map[string]*Device)
+	pending = make(map[string][]Command)
+	queuedAssets = make(map[string]string)
+	subscribers = make(map[string]map[*Subscription]struct{})
+	storePath = ""
+	dirty = false
+	mu.Unlock()
+
+	return filepath.Join(t.TempDir(), "config", "devices.json")
+}
+
+// TestValidID tests which device IDs are accepted from clients
+func TestValidID(t *testing.T) {
+	tests := []struct {
+		id   string
+		want bool
+	}{
+		{id: "0b6f0c3e-6f7a-4d59-9a55-2f1c9d7f3b10", want: true},
+		{id: "kitchen_frame", want: true},
+		{id: "", want: false},
+		{id: "a b", want: false},
+		{id: `"><script>`, want: false},
+		{id: string(make([]byte, maxIDLength+1)), want: false},
+	}
+
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, ValidID(tt.id), "id %q", tt.id)
+	}
+}
+
+// TestSeen tests that visits are recorded and missing details keep their last known value
+func TestSeen(t *testing.T) {
+	resetRegistry(t)
+
+	_, ok := Seen("bad id", Visit{})
+	assert.False(t, ok, "invalid IDs should not be registered")
+
+	first, ok := Seen("device-1", Visit{IP: "10.0.0.2", UserAgent: "Kiosk", Width: 1920, Height: 1080, DevicePixelRatio: 2})
+	assert.True(t, ok)
+	assert.Equal(t, "10.0.0.2", first.IP)
+	assert.Equal(t, 1920, first.Width)
+
+	second, _ := Seen("device-1", Visit{Name: "Kitchen", Client: "kitchen", IP: "10.0.0.3"})
+	assert.Equal(t, "Kitchen", second.Name)
+	assert.Equal(t, "kitchen", second.Client)
+	assert.Equal(t, "10.0.0.3", second.IP)
+	assert.Equal(t, "Kiosk", second.UserAgent, "user agent should be kept when not sent")
+	assert.Equal(t, 1920, second.Width, "size should be kept when not sent")
+	assert.Equal(t, 1080, second.Height, "size should be kept when not sent")
+	assert.Equal(t, first.FirstSeen, second.FirstSeen)
+
+	assets := []string{"asset-1", "asset-2"}
+	SetCurrentAssets("device-1", assets)
+	SetCurrentAssets("unknown", assets)
+	assets[0] = "changed"
+
+	device, ok := Get("device-1")
+	assert.True(t, ok)
+	assert.Equal(t, []string{"asset-1", "asset-2"}, device.CurrentAssets)
+
+	_, ok = Get("unknown")
+	assert.False(t, ok, "current assets should not register unknown devices")
+}
+
+// TestSaveAndLoad tests the registry survives a round trip through its JSON file
+func TestSaveAndLoad(t *testing.T) {
+	path := resetRegistry(t)
+
+	assert.NoError(t, Load(path), "a missing file should not be an error")
+
+	Seen("device-1", Visit{Name: "Kitchen", IP: "10.0.0.2", Width: 800, Height: 480})
+	Seen("device-2", Visit{IP: "10.0.0.4"})
+	SetCurrentAssets("device-1", []string{"asset-1"})
+
+	mu.Lock()
+	registry["stale"] = &Device{ID: "stale", LastSeen: time.Now().Add(-staleAfter - time.Hour)}
+	mu.Unlock()
+
+	assert.NoError(t, Save())
+
+	_, err := os.Stat(path)
+	assert.NoError(t, err, "the store should be created with its directory")
+
+	mu.Lock()
+	registry = make(map[string]*Device)
+	mu.Unlock()
+
+	assert.NoError(t, Load(path))
+
+	all := All()
+	assert.Len(t, all, 2, "stale devices should be removed when saving")
+	assert.Equal(t, "device-2", all[0].ID, "most recently seen device should be first")
+
+	device, ok := Get("device-1")
+	assert.True(t, ok)
+	assert.Equal(t, "Kitchen", device.Name)
+	assert.Equal(t, 800, device.Width)
+	assert.Equal(t, []string{"asset-1"}, device.CurrentAssets)
+}
+
+// TestRegistryLimits tests devices that never came back are removed and the registry never grows past maxDevices
+func TestRegistryLimits(t *testing.T) {
+	resetRegistry(t)
+
+	now := time.Now()
+
+	mu.Lock()
+	registry["returning"] = &Device{ID: "returning", FirstSeen: now.Add(-2 * time.Hour), LastSeen: now.Add(-2*time.Hour + time.Minute)}
+	registry["unconfirmed"] = &Device{ID: "unconfirmed", FirstSeen: now.Add(-2 * time.Hour), LastSeen: now.Add(-2 * time.Hour)}
+	registry["new"] = &Device{ID: "new", FirstSeen: now, LastSeen: now}
+	pending["unconfirmed"] = []Command{{Action: ActionNext}}
+	prune(now)
+	mu.Unlock()
+
+	assert.True(t, Known("returning"))
+	assert.True(t, Known("new"), "devices should have time to come back after their first visit")
+	assert.False(t, Known("unconfirmed"), "devices that never came back should be removed")
+	assert.Empty(t, TakeCommands("unconfirmed"), "commands for removed devices should be dropped")
+
+	mu.Lock()
+	for i := len(registry); i < maxDevices; i++ {
+		id := fmt.Sprintf("device-%d", i)
+		registry[id] = &Device{ID: id, FirstSeen: now.Add(-time.Hour), LastSeen: now.Add(-time.Duration(i) * time.Second)}
+	}
+	mu.Unlock()
+
+	Seen("one-more", Visit{})
+
+	assert.Len(t, All(), maxDevices, "the registry should not grow past maxDevices")
+	assert.True(t, Known("one-more"))
+	assert.False(t, Known("returning"), "the least recently seen device should make room")
+	assert.True(t, Known(fmt.Sprintf("device-%d", maxDevices-1)))
+}
+
+// TestCommands tests commands are queued once per device, cleared when taken and keep the server's device state in step
+func TestCommands(t *testing.T) {
+	resetRegistry(t)
+
+	next := Command{Action: ActionNext}
+	assert.False(t, SendCommand("unknown", next), "commands for unknown devices should be refused")
+
+	Seen("device-1", Visit{Client: "Kitchen"})
+	Seen("device-2", Visit{Client: "hallway"})
+
+	assert.True(t, SendCommand("device-1", next))
+	assert.True(t, SendCommand("device-1", next))
+	assert.True(t, SendCommand("device-1", Command{Action: ActionSleep}))
+	assert.True(t, IsSleeping("device-1"))
+
+	assert.Equal(t, []Command{next, {Action: ActionSleep}}, TakeCommands("device-1"))
+	assert.Empty(t, TakeCommands("device-1"), "commands should only be delivered once")
+
+	SendCommand("device-1", Command{Action: ActionWake})
+	assert.False(t, IsSleeping("device-1"))
+
+	show := Command{Action: ActionShowAsset, AssetID: "asset-1"}
+	assert.Equal(t, []string{"device-1"}, SendClientCommand("kitchen", show), "clients should match case-insensitively")
+	assert.Empty(t, SendClientCommand("unknown", show))
+
+	assetID, ok := TakeQueuedAsset("device-1")
+	assert.True(t, ok)
+	assert.Equal(t, "asset-1", assetID)
+
+	_, ok = TakeQueuedAsset("device-1")
+	assert.False(t, ok, "queued assets should only be shown once")
+}
+
+// TestSendCommandOrder tests repeated commands keep the order they were sent in,
+// so a device ends up in the same sleep state as the registry
+func TestSendCommandOrder(t *testing.T) {
+	resetRegistry(t)
+
+	Seen("device-1", Visit{})
+
+	sleep := Command{Action: ActionSleep}
+	wake := Command{Action: ActionWake}
+
+	for _, command := range []Command{sleep, wake, sleep} {
+		SendCommand("device-1", command)
+	}
+	assert.True(t, IsSleeping("device-1"))
+	assert.Equal(t, []Command{sleep, wake, sleep}, TakeCommands("device-1"))
+
+	subscription := Subscribe("device-1")
+	defer subscription.Close()
+
+	for _, command := range []Command{sleep, wake, sleep} {
+		SendCommand("device-1", command)
+	}
+	assert.True(t, IsSleeping("device-1"))
+	assert.Equal(t, []Command{sleep, wake, sleep}, subscription.TakeCommands(), "push connections should keep the order too")
+}
+
+// TestCommandValidate tests which commands are accepted
+func TestCommandValidate(t *testing.T) {
+	assert.NoError(t, Command{Action: ActionPause}.Validate())
+	assert.NoError(t, Command{Action: ActionShowAsset, AssetID: "asset-1"}.Validate())
+	assert.Error(t, Command{Action: "dance"}.Validate())
+	assert.Error(t, Command{Action: ActionShowAsset}.Validate(), "show_asset needs an asset")
+}
+
+// TestOnline tests devices count as online only when seen recently
+func TestOnline(t *testing.T) {
+	now := time.Now()
+
+	assert.True(t, Device{LastSeen: now.Add(-10 * time.Second)}.Online(now))
+	assert.False(t, Device{LastSeen: now.Add(-onlineWindow - time.Second)}.Online(now))
+	assert.False(t, Device{}.Online(now))
+}
+
+// TestSubscribe tests push connections are woken and handed every command queued for their device
+func TestSubscribe(t *testing.T) {
+	resetRegistry(t)
+
+	Seen("device-1", Visit{})
+	pause := Command{Action: ActionPause}
+	SendCommand("device-1", pause)
+
+	first := Subscribe("device-1")
+	assert.True(t, Connected("device-1"))
+
+	select {
+	case <-first.Wake():
+	default:
+		t.Fatal("commands queued before connecting should wake the connection")
+	}
+	assert.Equal(t, []Command{pause}, first.TakeCommands())
+	assert.Empty(t, TakeCommands("device-1"), "commands handed to a connection should not be delivered again on check in")
+
+	second := Subscribe("device-1")
+
+	next := Command{Action: ActionNext}
+	resume := Command{Action: ActionResume}
+	SendCommand("device-1", next)
+	SendCommand("device-1", next)
+	SendCommand("device-1", resume)
+	SendCommand("device-1", next)
+	assert.Len(t, first.Wake(), 1, "notifications should not block or pile up")
+	assert.Len(t, second.Wake(), 1, "notifications should not block or pile up")
+
+	assert.Equal(t, []Command{next, resume, next}, first.TakeCommands(), "every connection should receive every command")
+	assert.Equal(t, []Command{next, resume, next}, second.TakeCommands(), "every connection should receive every command")
+	assert.Empty(t, first.TakeCommands(), "commands should only be delivered once per connection")
+
+	first.Close()
+	assert.True(t, Connected("device-1"))
+	second.Close()
+	assert.False(t, Connected("device-1"))
+
+	SendCommand("device-1", next)
+	assert.Equal(t, []Command{next}, TakeCommands("device-1"), "commands should wait for check in once every connection is closed")
+}
@@ -0,0 +1,203 @@
+// Package groups schedules synchronized slideshows for groups of devices.
+//
+// The server, not each browser, decides when slides change. Time is divided into slots the length of
+// the refresh interval, aligned to the wall clock, so every device in a group changes slide at the same moment.
+// The slides for each slot are built once and shared by every device in the group.
+package groups
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+)
+
+const (
+	// maxLead is the longest time before a slot starts that devices request its slide,
+	// so the slide is ready to show when the slot starts
+	maxLead = 2 * time.Second
+	// memberTimeout is how many intervals a device stays in a group without requesting a slide
+	memberTimeout = 3
+)
+
+var (
+	mu     sync.Mutex
+	groups = make(map[string]*group)
+)
+
+// group holds the state of a single group
+type group struct {
+	// members when each device in the group last requested a slide, by device ID
+	members map[string]time.Time
+	// slides the slides built for the current and upcoming slots
+	slides map[slideKey]*slide
+}
+
+// slideKey identifies the slide shown at a position in the group during a slot
+type slideKey struct {
+	slot     int64
+	position int
+}
+
+// slide is a slide shared by the devices in a group. ready is closed once it has been built.
+type slide struct {
+	ready chan struct{}
+	value any
+	err   error
+}
+
+// ErrSlideType is returned when a slide was built as a different type, e.g. by a device with different group settings
+var ErrSlideType = errors.New("group slide was built with different settings")
+
+// Slot is a period of the schedule during which a group shows one slide
+type Slot struct {
+	// Index the number of intervals since the Unix epoch
+	Index int64
+	// Start when the slot starts
+	Start time.Time
+	// Interval how long the slot lasts
+	Interval time.Duration
+}
+
+// Lead returns how long before a slot starts its slide is requested.
+func Lead(interval time.Duration) time.Duration {
+	return min(maxLead, interval/4)
+}
+
+// SlotFor returns the slot a device requesting a slide at now should show.
+// This is the current slot, or the next one if it starts within the lead time.
+func SlotFor(now time.Time, interval time.Duration) Slot {
+	index := now.Add(Lead(interval)).UnixNano() / int64(interval)
+
+	return Slot{
+		Index:    index,
+		Start:    time.Unix(0, index*int64(interval)),
+		Interval: interval,
+	}
+}
+
+// End returns when the slot ends and the next one starts.
+func (s Slot) End() time.Time {
+	return s.Start.Add(s.Interval)
+}
+
+// ShowIn returns how long after now the slot's slide should be shown, 0 if the slot has already started.
+func (s Slot) ShowIn(now time.Time) time.Duration {
+	return max(0, s.Start.Sub(now))
+}
+
+// NextRequestIn returns how long after now the slide for the following slot should be requested.
+func (s Slot) NextRequestIn(now time.Time) time.Duration {
+	return max(0, s.End().Add(-Lead(s.Interval)).Sub(now))
+}
+
+// Join records that the device with the given ID requested a slide from the named group at now.
+// It returns the device's position among the group's current members, which are ordered by device ID
+// so each device keeps its position while the group's membership is unchanged.
+func Join(name, deviceID string, now time.Time, interval time.Duration) int {
+	mu.Lock()
+	defer mu.Unlock()
+
+	g := getGroup(name)
+	g.members[deviceID] = now
+
+	memberIDs := make([]string, 0, len(g.members))
+	for id, lastSeen := range g.members {
+		if now.Sub(lastSeen) > memberTimeout*interval {
+			delete(g.members, id)
+			continue
+		}
+		memberIDs = append(memberIDs, id)
+	}
+
+	slices.Sort(memberIDs)
+
+	return slices.Index(memberIDs, deviceID)
+}
+
+// Slide returns the slide shown at position in the named group during slot, calling build the first time it is requested.
+// Devices requesting the slide while it is being built wait for it, so they all show the same slide.
+// Failed builds, including builds that panic, are not kept so the next request tries again.
+func Slide[T any](name string, slot Slot, position int, build func() (T, error)) (T, error) {
+	key := slideKey{slot: slot.Index, position: position}
+
+	mu.Lock()
+	g := getGroup(name)
+
+	s, ok := g.slides[key]
+	if !ok {
+		s = &slide{ready: make(chan struct{})}
+		g.slides[key] = s
+
+		// slides for slots that have passed are no longer needed
+		for k := range g.slides {
+			if k.slot < slot.Index-1 {
+				delete(g.slides, k)
+			}
+		}
+	}
+	mu.Unlock()
+
+	if ok {
+		<-s.ready
+		return slideValue[T](s)
+	}
+
+	value, err := buildSlide(s, build)
+
+	if err != nil {
+		mu.Lock()
+		if g.slides[key] == s {
+			delete(g.slides, key)
+		}
+		mu.Unlock()
+	}
+
+	return value, err
+}
+
+// buildSlide builds the slide and closes ready, even if build panics,
+// so devices waiting for the slide are never left waiting. A panic is returned as an error.
+func buildSlide[T any](s *slide, build func() (T, error)) (value T, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("building group slide: %v", r)
+		}
+		s.value, s.err = value, err
+		close(s.ready)
+	}()
+
+	return build()
+}
+
+// slideValue returns the value of a built slide as T.
+func slideValue[T any](s *slide) (T, error) {
+	var zero T
+
+	if s.err != nil {
+		return zero, s.err
+	}
+
+	value, ok := s.value.(T)
+	if !ok {
+		return zero, ErrSlideType
+	}
+
+	return value, nil
+}
+
+// getGroup returns the named group, creating it if needed.
+// The caller must hold the lock.
+func getGroup(name string) *group {
+	g, ok := groups[name]
+	if !ok {
+		g = &group{
+			members: make(map[string]time.Time),
+			slides:  make(map[slideKey]*slide),
+		}
+		groups[name] = g
+	}
+
+	return g
+}
@@ -0,0 +1,130 @@
+package groups
+
+import (
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/damongolding/immich-kiosk/internal/common"
+	"github.com/damongolding/immich-kiosk/internal/config"
+)
+
+// resetGroups forgets every group
+func resetGroups(t *testing.T) {
+	t.Helper()
+
+	mu.Lock()
+	groups = make(map[string]*group)
+	mu.Unlock()
+}
+
+// TestSlotFor tests slots are aligned to the wall clock and the next slot is picked within the lead time
+func TestSlotFor(t *testing.T) {
+	interval := time.Minute
+	base := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	slot := SlotFor(base.Add(10*time.Second), interval)
+	assert.True(t, slot.Start.Equal(base))
+	assert.True(t, slot.End().Equal(base.Add(time.Minute)))
+	assert.Equal(t, time.Duration(0), slot.ShowIn(base.Add(10*time.Second)), "a slot that has started is shown straight away")
+	assert.Equal(t, 48*time.Second, slot.NextRequestIn(base.Add(10*time.Second)))
+
+	next := SlotFor(base.Add(59*time.Second), interval)
+	assert.Equal(t, slot.Index+1, next.Index, "the next slot should be requested within the lead time")
+	assert.Equal(t, time.Second, next.ShowIn(base.Add(59*time.Second)))
+
+	assert.Equal(t, 2*time.Second, Lead(time.Minute))
+	assert.Equal(t, time.Second, Lead(4*time.Second), "the lead should be shorter for short intervals")
+}
+
+// TestJoin tests members are ordered by device ID and leave the group once they stop requesting slides
+func TestJoin(t *testing.T) {
+	resetGroups(t)
+
+	now := time.Now()
+	interval := time.Minute
+
+	assert.Equal(t, 0, Join("room", "b", now, interval))
+	assert.Equal(t, 0, Join("room", "a", now, interval))
+	assert.Equal(t, 1, Join("room", "b", now, interval))
+	assert.Equal(t, 0, Join("other", "c", now, interval), "groups should be independent")
+
+	later := now.Add(memberTimeout*interval + time.Second)
+	assert.Equal(t, 0, Join("room", "b", later, interval), "members that stop requesting slides should leave")
+}
+
+// TestSlide tests a slide is built once per slot and position and shared by every device requesting it
+func TestSlide(t *testing.T) {
+	resetGroups(t)
+
+	slot := SlotFor(time.Now(), time.Minute)
+
+	var builds atomic.Int32
+	build := func() (common.ViewData, error) {
+		builds.Add(1)
+		time.Sleep(10 * time.Millisecond)
+		return common.ViewData{Config: config.Config{Layout: "single"}}, nil
+	}
+
+	var wg sync.WaitGroup
+	for range 5 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			viewData, err := Slide("room", slot, 0, build)
+			assert.NoError(t, err)
+			assert.Equal(t, "single", viewData.Layout)
+		}()
+	}
+	wg.Wait()
+
+	assert.Equal(t, int32(1), builds.Load(), "the slide should only be built once")
+
+	_, _ = Slide("room", slot, 1, build)
+	assert.Equal(t, int32(2), builds.Load(), "each position should have its own slide")
+
+	failing := func() (common.ViewData, error) {
+		builds.Add(1)
+		return common.ViewData{}, errors.New("immich unavailable")
+	}
+
+	_, err := Slide("room", slot, 2, failing)
+	assert.Error(t, err)
+
+	_, err = Slide("room", slot, 2, build)
+	assert.NoError(t, err, "failed slides should be built again")
+
+	_, err = Slide("room", slot, 2, func() (string, error) { return "tile", nil })
+	assert.ErrorIs(t, err, ErrSlideType, "slides should not be shared between different slide types")
+
+	started := make(chan struct{})
+	panicking := func() (common.ViewData, error) {
+		close(started)
+		time.Sleep(10 * time.Millisecond)
+		panic("decoding failed")
+	}
+
+	waited := make(chan error)
+	go func() {
+		<-started
+		_, err := Slide("room", slot, 3, build)
+		waited <- err
+	}()
+
+	_, err = Slide("room", slot, 3, panicking)
+	assert.ErrorContains(t, err, "decoding failed", "panics should be returned as errors")
+
+	select {
+	case err := <-waited:
+		assert.Error(t, err, "devices waiting for a slide that panicked should get the error")
+	case <-time.After(time.Second):
+		t.Fatal("devices waiting for a slide that panicked should not block")
+	}
+
+	_, err = Slide("room", slot, 3, build)
+	assert.NoError(t, err, "slides that panicked should be built again")
+}
@@ -34,6 +34,17 @@ const (
 	AssetSizeThumbnail string = "thumbnail"
 	AssetSizeOriginal  string = "original"
 
+	// Renditions Immich can serve, largest last
+	RenditionThumbnail string = "thumbnail"
+	RenditionPreview   string = "preview"
+	RenditionFullsize  string = "fullsize"
+	RenditionOriginal  string = "original"
+
+	// thumbnailRenditionSize and previewRenditionSize are the longest side (in pixels)
+	// of Immich's default thumbnail and preview renditions
+	thumbnailRenditionSize = 250
+	previewRenditionSize   = 1440
+
 	Asc  ImmichAssetOrder = "asc"
 	Desc ImmichAssetOrder = "desc"
 	Rand ImmichAssetOrder = "rand"
@@ -104,7 +115,7 @@ type ExifInfo struct {
 	State            string    `json:"state"`
 	Country          string    `json:"country"`
 	Description      string    `json:"description"`
-	ProjectionType   any       `json:"-"` // `json:"projectionType"`
+	ProjectionType   string    `json:"projectionType"`
 	ImageOrientation ImageOrientation
 }
 
@@ -128,6 +139,13 @@ type Face struct {
 	BoundingBoxY2 int    `json:"boundingBoxY2"`
 }
 
+// AssetFace is a face returned by the faces endpoint along with the person it is assigned to.
+// Person is nil for faces that have not been assigned to anyone.
+type AssetFace struct {
+	Face
+	Person *Person `json:"person"`
+}
+
 type ImmichAsset struct {
 	ID               string          `json:"id"`
 	DeviceAssetID    string          `json:"-"` // `json:"deviceAssetId"`
@@ -164,6 +182,15 @@ type ImmichAsset struct {
 	IsLandscape     bool             `json:"-"`
 	KioskSource     kiosk.Source     `json:"-"`
 	KioskSourceName string           `json:"-"`
+
+	// Selection diagnostics shown by the debug overlay
+	KioskSourceWeight  int           `json:"-"`
+	KioskTotalWeight   int           `json:"-"`
+	KioskSelectionTime time.Duration `json:"-"`
+
+	// requestConfig the config of the request the asset is for. It is kept on the asset,
+	// rather than the package, so concurrent requests each use their own client size and filters
+	requestConfig config.Config
 }
 
 type ImmichAlbum struct {
@@ -224,14 +251,20 @@ type MemoryLaneResponse []struct {
 	Assets   []ImmichAsset `json:"assets"`
 }
 
-// NewImage returns a new image instance
+// NewImage returns a new image instance for a request using the given config
 func NewImage(base config.Config) ImmichAsset {
 	requestConfig = base
-	return ImmichAsset{}
+	return ImmichAsset{requestConfig: base}
+}
+
+// replace swaps the asset for another one, keeping the config of the request it is for.
+func (i *ImmichAsset) replace(asset ImmichAsset) {
+	asset.requestConfig = i.requestConfig
+	*i = asset
 }
 
 type ImmichApiCall func(string, string, []byte) ([]byte, error)
 
 type ImmichApiResponse interface {
-	ImmichAsset | []ImmichAsset | ImmichAlbum | ImmichAlbums | ImmichPersonStatistics | int | ImmichSearchMetadataResponse | []Face | immich_open_api.PersonResponseDto | MemoryLaneResponse
+	ImmichAsset | []ImmichAsset | ImmichAlbum | ImmichAlbums | ImmichPersonStatistics | int | ImmichSearchMetadataResponse | []Face | []AssetFace | immich_open_api.PersonResponseDto | MemoryLaneResponse
 }
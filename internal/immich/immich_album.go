@@ -207,8 +207,9 @@ func (i *ImmichAsset) ImageFromAlbum(albumID string, albumAssetsOrder ImmichAsse
 			isTrashed := asset.IsTrashed
 			isArchived := asset.IsArchived && !requestConfig.ShowArchived
 			isInvalidRatio := !i.ratioCheck(&asset)
+			isNonPhoto := i.isNonPhoto(&asset, requestID)
 
-			if isInvalidType || isTrashed || isArchived || isInvalidRatio {
+			if isInvalidType || isTrashed || isArchived || isInvalidRatio || isNonPhoto {
 				continue
 			}
 
@@ -230,7 +231,7 @@ func (i *ImmichAsset) ImageFromAlbum(albumID string, albumAssetsOrder ImmichAsse
 
 			}
 
-			*i = asset
+			i.replace(asset)
 
 			i.KioskSourceName = album.AlbumName
 
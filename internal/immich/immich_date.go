@@ -137,8 +137,9 @@ func (i *ImmichAsset) RandomImageInDateRange(dateRange, requestID, deviceID stri
 			isTrashed := img.IsTrashed
 			isArchived := img.IsArchived && !requestConfig.ShowArchived
 			isInvalidRatio := !i.ratioCheck(&img)
+			isNonPhoto := i.isNonPhoto(&img, requestID)
 
-			if isInvalidType || isTrashed || isArchived || isInvalidRatio {
+			if isInvalidType || isTrashed || isArchived || isInvalidRatio || isNonPhoto {
 				continue
 			}
 
@@ -160,7 +161,7 @@ func (i *ImmichAsset) RandomImageInDateRange(dateRange, requestID, deviceID stri
 
 			img.KioskSourceName = fmt.Sprintf("%s to %s", dateStartHuman, dateEndHuman)
 
-			*i = img
+			i.replace(img)
 
 			return nil
 		}
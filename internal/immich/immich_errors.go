@@ -0,0 +1,60 @@
+package immich
+
+import (
+	"net/url"
+	"slices"
+	"sync"
+	"time"
+)
+
+// maxApiErrors is how many recent Immich API errors are kept
+const maxApiErrors = 20
+
+var (
+	apiErrorsMu sync.Mutex
+	apiErrors   []ApiError
+)
+
+// ApiError is a failed call to the Immich API
+type ApiError struct {
+	// Time when the call failed
+	Time time.Time
+	// Method and Path of the failed call. Queries are left out of the path
+	Method string
+	Path   string
+	// Error why the call failed
+	Error string
+}
+
+// recordApiError adds a failed Immich API call to the recent errors, dropping the oldest when full.
+func recordApiError(method, apiUrl string, err error) {
+	path := apiUrl
+	if u, parseErr := url.Parse(apiUrl); parseErr == nil {
+		path = u.Path
+	}
+
+	apiErrorsMu.Lock()
+	defer apiErrorsMu.Unlock()
+
+	apiErrors = append(apiErrors, ApiError{
+		Time:   time.Now(),
+		Method: method,
+		Path:   path,
+		Error:  err.Error(),
+	})
+
+	if len(apiErrors) > maxApiErrors {
+		apiErrors = slices.Delete(apiErrors, 0, len(apiErrors)-maxApiErrors)
+	}
+}
+
+// RecentApiErrors returns the most recent failed Immich API calls, newest first.
+func RecentApiErrors() []ApiError {
+	apiErrorsMu.Lock()
+	defer apiErrorsMu.Unlock()
+
+	recent := slices.Clone(apiErrors)
+	slices.Reverse(recent)
+
+	return recent
+}
@@ -2,20 +2,30 @@ package immich
 
 import (
 	"encoding/json"
+	"fmt"
 	"net/url"
+	"slices"
 
 	"github.com/charmbracelet/log"
 )
 
+// CheckForFaces retrieves the faces Immich has detected in the asset, logging any error.
 func (i *ImmichAsset) CheckForFaces(requestID, deviceID string) {
+	if err := i.FetchFaces(requestID, deviceID); err != nil {
+		log.Error("adding faces", "err", err)
+	}
+}
 
-	var faces []Face
+// FetchFaces replaces the people and unassigned faces of the asset with the faces Immich has detected.
+// Faces assigned to a person are grouped under that person so their ID and name are kept.
+func (i *ImmichAsset) FetchFaces(requestID, deviceID string) error {
+
+	var faces []AssetFace
 
 	u, err := url.Parse(requestConfig.ImmichUrl)
 	if err != nil {
 		_, _, err = immichApiFail(faces, err, nil, "")
-		log.Error("parsing faces url", "err", err)
-		return
+		return fmt.Errorf("parsing faces url: %w", err)
 	}
 
 	apiUrl := url.URL{
@@ -29,21 +39,61 @@ func (i *ImmichAsset) CheckForFaces(requestID, deviceID string) {
 	body, err := immichApiCall("GET", apiUrl.String(), nil)
 	if err != nil {
 		_, _, err = immichApiFail(faces, err, body, apiUrl.String())
-		log.Error("adding faces", "err", err)
-		return
+		return err
 	}
 
 	err = json.Unmarshal(body, &faces)
 	if err != nil {
 		_, _, err = immichApiFail(faces, err, body, apiUrl.String())
-		log.Error("adding faces", "err", err)
-		return
+		return err
+	}
+
+	i.People, i.UnassignedFaces = groupFaces(faces)
+
+	return nil
+}
+
+// groupFaces splits faces into the people they are assigned to and unassigned faces.
+// People are returned in the order their first face appears.
+func groupFaces(faces []AssetFace) ([]Person, []Face) {
+	var people []Person
+	var unassigned []Face
+
+	for _, face := range faces {
+		if face.Person == nil || face.Person.ID == "" {
+			unassigned = append(unassigned, face.Face)
+			continue
+		}
+
+		index := slices.IndexFunc(people, func(p Person) bool { return p.ID == face.Person.ID })
+		if index == -1 {
+			person := *face.Person
+			person.Faces = nil
+			people = append(people, person)
+			index = len(people) - 1
+		}
+
+		people[index].Faces = append(people[index].Faces, face.Face)
 	}
 
-	p := Person{
-		Faces: faces,
+	return people, unassigned
+}
+
+// PrivateFaces returns the faces that should be blurred for privacy.
+// These are the unnamed faces (unassigned, or assigned to a person without a name) when blurUnnamed is set,
+// and the faces of any person whose ID is in people.
+func (i *ImmichAsset) PrivateFaces(blurUnnamed bool, people []string) []Face {
+	var faces []Face
+
+	if blurUnnamed {
+		faces = append(faces, i.UnassignedFaces...)
 	}
 
-	i.People = append(i.People, p)
+	for _, person := range i.People {
+		if (blurUnnamed && person.Name == "") || slices.Contains(people, person.ID) {
+			faces = append(faces, person.Faces...)
+		}
+	}
 
+	return faces
 }
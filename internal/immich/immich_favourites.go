@@ -180,8 +180,9 @@ func (i *ImmichAsset) RandomImageFromFavourites(requestID, deviceID string, isPr
 			isTrashed := img.IsTrashed
 			isArchived := img.IsArchived && !requestConfig.ShowArchived
 			isInvalidRatio := !i.ratioCheck(&img)
+			isNonPhoto := i.isNonPhoto(&img, requestID)
 
-			if isInvalidType || isTrashed || isArchived || isInvalidRatio {
+			if isInvalidType || isTrashed || isArchived || isInvalidRatio || isNonPhoto {
 				continue
 			}
 
@@ -201,7 +202,7 @@ func (i *ImmichAsset) RandomImageFromFavourites(requestID, deviceID string, isPr
 				}
 			}
 
-			*i = img
+			i.replace(img)
 			return nil
 		}
 
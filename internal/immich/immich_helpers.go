@@ -79,8 +79,17 @@ func immichApiCallDecorator[T ImmichApiResponse](immichApiCall ImmichApiCall, re
 	}
 }
 
-// immichApiCall bootstrap for immich api call
+// immichApiCall bootstrap for immich api call. Failed calls are recorded in the recent API errors.
 func (i *ImmichAsset) immichApiCall(method, apiUrl string, body []byte) ([]byte, error) {
+	responseBody, err := i.sendImmichApiCall(method, apiUrl, body)
+	if err != nil {
+		recordApiError(method, apiUrl, err)
+	}
+	return responseBody, err
+}
+
+// sendImmichApiCall sends a request to the Immich API, retrying requests that fail to send
+func (i *ImmichAsset) sendImmichApiCall(method, apiUrl string, body []byte) ([]byte, error) {
 
 	var responseBody []byte
 	var lastErr error
@@ -161,13 +170,18 @@ func (i *ImmichAsset) immichApiCall(method, apiUrl string, body []byte) ([]byte,
 }
 
 // ratioCheck checks if the given image matches the desired ratio.
-// It first adds the ratio information to the image, then checks if the ratio
+// It first adds the ratio information to the image, then checks the image against
+// the configured size and aspect ratio bounds, and finally if the ratio
 // matches the desired ratio (Portrait or Landscape) if specified.
 // If no specific ratio is wanted, it returns true.
 func (i *ImmichAsset) ratioCheck(img *ImmichAsset) bool {
 
 	img.addRatio()
 
+	if !i.sizeCheck(img) {
+		return false
+	}
+
 	// specific ratio is not wanted
 	if i.RatioWanted == "" {
 		return true
@@ -181,6 +195,59 @@ func (i *ImmichAsset) ratioCheck(img *ImmichAsset) bool {
 	return false
 }
 
+// sizeCheck checks the image against the request's minimum width, height, megapixels
+// and aspect ratio bounds, using the dimensions of the image as displayed (after EXIF rotation).
+// Images without dimension information always pass.
+func (i *ImmichAsset) sizeCheck(img *ImmichAsset) bool {
+	width, height := img.displayDimensions()
+	if width == 0 || height == 0 {
+		return true
+	}
+
+	aspectRatio := float64(width) / float64(height)
+
+	switch {
+	case i.requestConfig.MinWidth > 0 && width < i.requestConfig.MinWidth:
+		return false
+	case i.requestConfig.MinHeight > 0 && height < i.requestConfig.MinHeight:
+		return false
+	case i.requestConfig.MinMegapixels > 0 && float64(width*height)/1_000_000 < i.requestConfig.MinMegapixels:
+		return false
+	case i.requestConfig.MinAspectRatio > 0 && aspectRatio < i.requestConfig.MinAspectRatio:
+		return false
+	case i.requestConfig.MaxAspectRatio > 0 && aspectRatio > i.requestConfig.MaxAspectRatio:
+		return false
+	}
+
+	return true
+}
+
+// IsPanorama reports whether the image is a panorama, either because Immich reports a
+// projection type (e.g. equirectangular) or because its displayed aspect ratio is at least minRatio.
+func (i *ImmichAsset) IsPanorama(minRatio float64) bool {
+	if i.ExifInfo.ProjectionType != "" {
+		return true
+	}
+
+	width, height := i.displayDimensions()
+	if width == 0 || height == 0 || minRatio <= 0 {
+		return false
+	}
+
+	return float64(width)/float64(height) >= minRatio
+}
+
+// displayDimensions returns the width and height of the image as displayed.
+// For orientations 5, 6, 7, and 8, the image is rotated by 90 degrees so width and height are swapped.
+func (i *ImmichAsset) displayDimensions() (int, int) {
+	switch i.ExifInfo.Orientation {
+	case "5", "6", "7", "8":
+		return i.ExifInfo.ExifImageHeight, i.ExifInfo.ExifImageWidth
+	default:
+		return i.ExifInfo.ExifImageWidth, i.ExifInfo.ExifImageHeight
+	}
+}
+
 // addRatio determines the ratio (portrait or landscape) of the image based on its EXIF information.
 // It sets the Ratio field in ExifInfo and updates IsPortrait or IsLandscape accordingly.
 // For orientations 5, 6, 7, and 8, it considers the image rotated by 90 degrees.
@@ -208,7 +275,7 @@ func (i *ImmichAsset) addRatio() {
 	}
 }
 
-// AssetInfo fetches the image information from Immich
+// AssetInfo fetches the image information from Immich, including the image's orientation
 func (i *ImmichAsset) AssetInfo(requestID, deviceID string) error {
 
 	var immichAsset ImmichAsset
@@ -237,13 +304,29 @@ func (i *ImmichAsset) AssetInfo(requestID, deviceID string) error {
 		return fmt.Errorf("fetching asset info: err %v", err)
 	}
 
-	*i = immichAsset
+	i.replace(immichAsset)
+	i.addRatio()
 
 	return nil
 }
 
-// ImagePreview fetches the raw image data from Immich
+// ImagePreview fetches the raw image data from Immich.
+// The rendition is picked to suit the client's resolution (see Rendition).
+// If the fullsize rendition is not available (older Immich versions) the preview is used instead.
 func (i *ImmichAsset) ImagePreview() ([]byte, error) {
+	rendition := i.Rendition()
+
+	bytes, err := i.ImageRendition(rendition)
+	if err != nil && rendition == RenditionFullsize {
+		log.Debug("Fullsize rendition unavailable, falling back to preview", "id", i.ID, "err", err)
+		return i.ImageRendition(RenditionPreview)
+	}
+
+	return bytes, err
+}
+
+// ImageRendition fetches the raw image data of a specific rendition from Immich
+func (i *ImmichAsset) ImageRendition(rendition string) ([]byte, error) {
 
 	var bytes []byte
 
@@ -253,19 +336,68 @@ func (i *ImmichAsset) ImagePreview() ([]byte, error) {
 		return bytes, err
 	}
 
-	assetSize := AssetSizeThumbnail
-	if requestConfig.UseOriginalImage && slices.Contains(supportedImageMimeTypes, i.OriginalMimeType) {
-		assetSize = AssetSizeOriginal
+	return i.immichApiCall("GET", i.renditionUrl(u, rendition), nil)
+}
+
+// Rendition picks which rendition of the image to request from Immich.
+//   - the original is used when use_original_image is set, as long as it is a png, gif, jpeg or webp
+//   - otherwise the smallest of thumbnail, preview or fullsize that covers the client's
+//     screen (at its device pixel ratio), or the whole image if it is smaller than the screen, is used
+//
+// Fullsize is the image at its own resolution, so the original never has more pixels to offer
+// and is not picked for large screens. Panoramas are shown at the screen height so always use fullsize.
+// Without client dimensions the preview is used.
+func (i *ImmichAsset) Rendition() string {
+	if i.requestConfig.UseOriginalImage && slices.Contains(supportedImageMimeTypes, i.OriginalMimeType) {
+		return RenditionOriginal
+	}
+
+	if i.requestConfig.PanoramaMode && i.IsPanorama(i.requestConfig.PanoramaMinRatio) {
+		return RenditionFullsize
 	}
 
+	width, height := i.requestConfig.ClientData.Width, i.requestConfig.ClientData.Height
+	if width <= 0 || height <= 0 {
+		return RenditionPreview
+	}
+
+	dpr := i.requestConfig.ClientData.DevicePixelRatio
+	if dpr <= 0 {
+		dpr = 1
+	}
+
+	needed := float64(max(width, height)) * dpr
+
+	// no rendition is larger than the image itself
+	if longest := max(i.ExifInfo.ExifImageWidth, i.ExifInfo.ExifImageHeight); longest > 0 {
+		needed = min(needed, float64(longest))
+	}
+
+	switch {
+	case needed <= thumbnailRenditionSize:
+		return RenditionThumbnail
+	case needed <= previewRenditionSize:
+		return RenditionPreview
+	default:
+		return RenditionFullsize
+	}
+}
+
+// renditionUrl builds the Immich API url for a rendition of the image.
+func (i *ImmichAsset) renditionUrl(u *url.URL, rendition string) string {
 	apiUrl := url.URL{
-		Scheme:   u.Scheme,
-		Host:     u.Host,
-		Path:     path.Join("api", "assets", i.ID, assetSize),
-		RawQuery: "size=preview",
+		Scheme: u.Scheme,
+		Host:   u.Host,
 	}
 
-	return i.immichApiCall("GET", apiUrl.String(), nil)
+	if rendition == RenditionOriginal {
+		apiUrl.Path = path.Join("api", "assets", i.ID, AssetSizeOriginal)
+	} else {
+		apiUrl.Path = path.Join("api", "assets", i.ID, AssetSizeThumbnail)
+		apiUrl.RawQuery = "size=" + rendition
+	}
+
+	return apiUrl.String()
 }
 
 // FacesCenterPoint calculates the center point of all detected faces in an image as percentages.
@@ -411,3 +543,45 @@ func (i *ImmichAsset) FacesCenterPointPX() (float64, float64) {
 
 	return centerX, centerY
 }
+
+// FacesBoundingBox returns the box that encompasses all detected faces in an image as percentages
+// of the image dimensions (x1, y1, x2, y2). Each face is scaled by the image size it was detected on.
+// The last return value is false if no faces with valid bounding boxes and image dimensions are found.
+func (i *ImmichAsset) FacesBoundingBox() (float64, float64, float64, float64, bool) {
+	faces := slices.Clone(i.UnassignedFaces)
+	for _, person := range i.People {
+		faces = append(faces, person.Faces...)
+	}
+
+	var minX, minY, maxX, maxY float64
+	found := false
+
+	for _, face := range faces {
+		if face.ImageWidth == 0 || face.ImageHeight == 0 {
+			continue
+		}
+
+		if face.BoundingBoxX1 == 0 && face.BoundingBoxY1 == 0 &&
+			face.BoundingBoxX2 == 0 && face.BoundingBoxY2 == 0 {
+			continue
+		}
+
+		x1 := float64(face.BoundingBoxX1) / float64(face.ImageWidth) * 100
+		y1 := float64(face.BoundingBoxY1) / float64(face.ImageHeight) * 100
+		x2 := float64(face.BoundingBoxX2) / float64(face.ImageWidth) * 100
+		y2 := float64(face.BoundingBoxY2) / float64(face.ImageHeight) * 100
+
+		if !found {
+			minX, minY, maxX, maxY = x1, y1, x2, y2
+			found = true
+			continue
+		}
+
+		minX = min(minX, x1)
+		minY = min(minY, y1)
+		maxX = max(maxX, x2)
+		maxY = max(maxY, y2)
+	}
+
+	return minX, minY, maxX, maxY, found
+}
@@ -110,8 +110,9 @@ func (i *ImmichAsset) RandomMemoryLaneImage(requestID, deviceID string, isPrefet
 			isTrashed := asset.IsTrashed
 			isArchived := asset.IsArchived && !requestConfig.ShowArchived
 			isInvalidRatio := !i.ratioCheck(&asset)
+			isNonPhoto := i.isNonPhoto(&asset, requestID)
 
-			if isInvalidType || isTrashed || isArchived || isInvalidRatio {
+			if isInvalidType || isTrashed || isArchived || isInvalidRatio || isNonPhoto {
 				continue
 			}
 
@@ -146,7 +147,7 @@ func (i *ImmichAsset) RandomMemoryLaneImage(requestID, deviceID string, isPrefet
 
 			}
 
-			*i = asset
+			i.replace(asset)
 
 			i.KioskSourceName = memories[pickedMemoryIndex].Title
 
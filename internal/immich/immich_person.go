@@ -130,8 +130,9 @@ func (i *ImmichAsset) RandomImageOfPerson(personID, requestID, deviceID string,
 			isTrashed := img.IsTrashed
 			isArchived := img.IsArchived && !requestConfig.ShowArchived
 			isInvalidRatio := !i.ratioCheck(&img)
+			isNonPhoto := i.isNonPhoto(&img, requestID)
 
-			if isInvalidType || isTrashed || isArchived || isInvalidRatio {
+			if isInvalidType || isTrashed || isArchived || isInvalidRatio || isNonPhoto {
 				continue
 			}
 
@@ -151,7 +152,7 @@ func (i *ImmichAsset) RandomImageOfPerson(personID, requestID, deviceID string,
 				}
 			}
 
-			*i = img
+			i.replace(img)
 
 			i.PersonName(personID)
 
@@ -100,8 +100,9 @@ func (i *ImmichAsset) RandomImage(requestID, deviceID string, isPrefetch bool) e
 			isTrashed := img.IsTrashed
 			isArchived := img.IsArchived && !requestConfig.ShowArchived
 			isInvalidRatio := !i.ratioCheck(&img)
+			isNonPhoto := i.isNonPhoto(&img, requestID)
 
-			if isInvalidType || isTrashed || isArchived || isInvalidRatio {
+			if isInvalidType || isTrashed || isArchived || isInvalidRatio || isNonPhoto {
 				continue
 			}
 
@@ -121,7 +122,7 @@ func (i *ImmichAsset) RandomImage(requestID, deviceID string, isPrefetch bool) e
 				}
 			}
 
-			*i = img
+			i.replace(img)
 			return nil
 		}
 
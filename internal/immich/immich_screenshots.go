@@ -0,0 +1,78 @@
+package immich
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/charmbracelet/log"
+)
+
+// nonPhotoFileNamePattern matches file names given to screenshots, screen recordings and scanned documents
+var nonPhotoFileNamePattern = regexp.MustCompile(`(?i)(screen[ _-]?shot|screen[ _-]?recording|camscanner|scanned|^scan[ _-]|^receipt|^document[ _-])`)
+
+// screenResolutions are common phone, tablet and monitor resolutions (width x height in landscape).
+// Images without camera information at exactly these sizes are most likely screenshots.
+var screenResolutions = [][2]int{
+	// monitors and laptops
+	{1280, 720}, {1280, 800}, {1366, 768}, {1440, 900}, {1536, 864},
+	{1680, 1050}, {1920, 1080}, {1920, 1200}, {2560, 1440}, {2560, 1600},
+	{2880, 1800}, {3024, 1964}, {3456, 2234}, {3840, 2160}, {5120, 2880},
+	// phones
+	{1334, 750}, {1792, 828}, {2208, 1242}, {2436, 1125}, {2532, 1170},
+	{2556, 1179}, {2688, 1242}, {2778, 1284}, {2796, 1290}, {2340, 1080},
+	{2400, 1080}, {2520, 1080}, {3088, 1440}, {3120, 1440}, {3200, 1440},
+	// tablets
+	{2048, 1536}, {2224, 1668}, {2360, 1640}, {2388, 1668}, {2732, 2048},
+}
+
+// NonPhotoReason returns why an asset looks like a screenshot or document rather than a photo.
+// It returns an empty string if the asset looks like a photo.
+//
+// The checks are:
+//   - the file name matches a screenshot, screen recording or scanner pattern
+//   - the asset is a PNG without camera information
+//   - the asset has no camera information and is exactly the size of a common screen
+func (i *ImmichAsset) NonPhotoReason() string {
+	if nonPhotoFileNamePattern.MatchString(i.OriginalFileName) {
+		return "file name " + i.OriginalFileName
+	}
+
+	hasCamera := strings.TrimSpace(i.ExifInfo.Make) != "" || strings.TrimSpace(i.ExifInfo.Model) != ""
+	if hasCamera {
+		return ""
+	}
+
+	if strings.EqualFold(i.OriginalMimeType, "image/png") {
+		return "png without camera information"
+	}
+
+	width, height := i.ExifInfo.ExifImageWidth, i.ExifInfo.ExifImageHeight
+	if height > width {
+		width, height = height, width
+	}
+
+	for _, resolution := range screenResolutions {
+		if width == resolution[0] && height == resolution[1] {
+			return "screen sized without camera information"
+		}
+	}
+
+	return ""
+}
+
+// isNonPhoto reports whether an asset should be skipped because it looks like a
+// screenshot or document and exclude_screenshots is enabled.
+func (i *ImmichAsset) isNonPhoto(img *ImmichAsset, requestID string) bool {
+	if !i.requestConfig.ExcludeScreenshots {
+		return false
+	}
+
+	reason := img.NonPhotoReason()
+	if reason == "" {
+		return false
+	}
+
+	log.Debug(requestID+" Skipping non-photo asset", "id", img.ID, "reason", reason)
+
+	return true
+}
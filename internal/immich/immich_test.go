@@ -1,8 +1,11 @@
 package immich
 
 import (
+	"errors"
+	"fmt"
 	"testing"
 
+	"github.com/damongolding/immich-kiosk/internal/config"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -222,3 +225,270 @@ func TestRemoveExcludedAlbums(t *testing.T) {
 		})
 	}
 }
+
+// TestFacesBoundingBox tests the box encompassing all detected faces in an asset
+func TestFacesBoundingBox(t *testing.T) {
+	t.Run("No faces", func(t *testing.T) {
+		asset := ImmichAsset{}
+		_, _, _, _, ok := asset.FacesBoundingBox()
+		assert.False(t, ok)
+	})
+
+	t.Run("Assigned and unassigned faces", func(t *testing.T) {
+		asset := ImmichAsset{
+			People: []Person{
+				{Faces: []Face{{ImageWidth: 1000, ImageHeight: 500, BoundingBoxX1: 100, BoundingBoxY1: 50, BoundingBoxX2: 200, BoundingBoxY2: 150}}},
+			},
+			UnassignedFaces: []Face{
+				{ImageWidth: 1000, ImageHeight: 500, BoundingBoxX1: 500, BoundingBoxY1: 100, BoundingBoxX2: 600, BoundingBoxY2: 250},
+				// faces without image dimensions are ignored
+				{BoundingBoxX1: 900, BoundingBoxY1: 400, BoundingBoxX2: 950, BoundingBoxY2: 450},
+			},
+		}
+
+		x1, y1, x2, y2, ok := asset.FacesBoundingBox()
+		assert.True(t, ok)
+		assert.InDelta(t, 10, x1, 0.001)
+		assert.InDelta(t, 10, y1, 0.001)
+		assert.InDelta(t, 60, x2, 0.001)
+		assert.InDelta(t, 50, y2, 0.001)
+	})
+}
+
+// TestPrivateFaces tests grouping faces by person and picking the faces to blur
+func TestPrivateFaces(t *testing.T) {
+	named := Face{ID: "named", ImageWidth: 100, ImageHeight: 100}
+	unnamed := Face{ID: "unnamed", ImageWidth: 100, ImageHeight: 100}
+	unassigned := Face{ID: "unassigned", ImageWidth: 100, ImageHeight: 100}
+
+	people, unassignedFaces := groupFaces([]AssetFace{
+		{Face: named, Person: &Person{ID: "alice", Name: "Alice"}},
+		{Face: unassigned},
+		{Face: unnamed, Person: &Person{ID: "stranger"}},
+		{Face: named, Person: &Person{ID: "alice", Name: "Alice"}},
+	})
+
+	assert.Len(t, people, 2)
+	assert.Equal(t, "alice", people[0].ID)
+	assert.Len(t, people[0].Faces, 2, "Faces of the same person should be grouped")
+	assert.Equal(t, []Face{unassigned}, unassignedFaces)
+
+	asset := ImmichAsset{People: people, UnassignedFaces: unassignedFaces}
+
+	assert.Empty(t, asset.PrivateFaces(false, nil))
+	assert.Equal(t, []Face{unassigned, unnamed}, asset.PrivateFaces(true, nil))
+	assert.Equal(t, []Face{named, named}, asset.PrivateFaces(false, []string{"alice"}))
+	assert.Len(t, asset.PrivateFaces(true, []string{"alice"}), 4)
+}
+
+// TestNonPhotoReason tests the screenshot and document detection heuristics
+func TestNonPhotoReason(t *testing.T) {
+	tests := []struct {
+		name      string
+		asset     ImmichAsset
+		wantPhoto bool
+	}{
+		{
+			name:      "Camera photo",
+			asset:     ImmichAsset{OriginalFileName: "IMG_1234.JPG", OriginalMimeType: "image/jpeg", ExifInfo: ExifInfo{Make: "Apple", Model: "iPhone 15", ExifImageWidth: 4032, ExifImageHeight: 3024}},
+			wantPhoto: true,
+		},
+		{
+			name:      "Screenshot file name",
+			asset:     ImmichAsset{OriginalFileName: "Screenshot_20240101-101010.jpg", ExifInfo: ExifInfo{Make: "Google"}},
+			wantPhoto: false,
+		},
+		{
+			name:      "Mac screenshot file name",
+			asset:     ImmichAsset{OriginalFileName: "Screen Shot 2020-01-01 at 10.00.00.png"},
+			wantPhoto: false,
+		},
+		{
+			name:      "Scanned document",
+			asset:     ImmichAsset{OriginalFileName: "CamScanner 01-01-2024.jpg"},
+			wantPhoto: false,
+		},
+		{
+			name:      "PNG without camera",
+			asset:     ImmichAsset{OriginalFileName: "image.png", OriginalMimeType: "image/png"},
+			wantPhoto: false,
+		},
+		{
+			name:      "Phone screen size without camera",
+			asset:     ImmichAsset{OriginalFileName: "IMG_0001.JPG", OriginalMimeType: "image/jpeg", ExifInfo: ExifInfo{ExifImageWidth: 1170, ExifImageHeight: 2532}},
+			wantPhoto: false,
+		},
+		{
+			name:      "No camera but not screen sized",
+			asset:     ImmichAsset{OriginalFileName: "IMG-20240101-WA0001.jpg", OriginalMimeType: "image/jpeg", ExifInfo: ExifInfo{ExifImageWidth: 1600, ExifImageHeight: 1200}},
+			wantPhoto: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reason := tt.asset.NonPhotoReason()
+			assert.Equal(t, tt.wantPhoto, reason == "", reason)
+		})
+	}
+}
+
+// TestSizeCheck tests the minimum size and aspect ratio filters
+func TestSizeCheck(t *testing.T) {
+	originalConfig := requestConfig
+	t.Cleanup(func() { requestConfig = originalConfig })
+
+	tests := []struct {
+		name   string
+		config config.Config
+		exif   ExifInfo
+		want   bool
+	}{
+		{
+			name:   "No filters",
+			config: config.Config{},
+			exif:   ExifInfo{ExifImageWidth: 640, ExifImageHeight: 480},
+			want:   true,
+		},
+		{
+			name:   "Too narrow",
+			config: config.Config{MinWidth: 1000},
+			exif:   ExifInfo{ExifImageWidth: 800, ExifImageHeight: 1200},
+			want:   false,
+		},
+		{
+			name:   "Rotated image uses displayed width",
+			config: config.Config{MinWidth: 1000},
+			exif:   ExifInfo{ExifImageWidth: 800, ExifImageHeight: 1200, Orientation: "6"},
+			want:   true,
+		},
+		{
+			name:   "Too few megapixels",
+			config: config.Config{MinMegapixels: 2},
+			exif:   ExifInfo{ExifImageWidth: 1600, ExifImageHeight: 1200},
+			want:   false,
+		},
+		{
+			name:   "Enough megapixels",
+			config: config.Config{MinMegapixels: 2, MinHeight: 1000},
+			exif:   ExifInfo{ExifImageWidth: 2000, ExifImageHeight: 1500},
+			want:   true,
+		},
+		{
+			name:   "Panorama too wide",
+			config: config.Config{MaxAspectRatio: 3},
+			exif:   ExifInfo{ExifImageWidth: 8000, ExifImageHeight: 2000},
+			want:   false,
+		},
+		{
+			name:   "Rotated panorama too tall",
+			config: config.Config{MinAspectRatio: 0.33},
+			exif:   ExifInfo{ExifImageWidth: 8000, ExifImageHeight: 2000, Orientation: "8"},
+			want:   false,
+		},
+		{
+			name:   "Unknown dimensions pass",
+			config: config.Config{MinWidth: 1000, MinMegapixels: 2},
+			exif:   ExifInfo{},
+			want:   true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			asset := NewImage(tt.config)
+			assert.Equal(t, tt.want, asset.sizeCheck(&ImmichAsset{ExifInfo: tt.exif}))
+		})
+	}
+}
+
+// TestIsPanorama tests panorama detection from projection type and aspect ratio
+func TestIsPanorama(t *testing.T) {
+	tests := []struct {
+		name string
+		exif ExifInfo
+		want bool
+	}{
+		{name: "Regular landscape", exif: ExifInfo{ExifImageWidth: 4000, ExifImageHeight: 3000}, want: false},
+		{name: "Wide panorama", exif: ExifInfo{ExifImageWidth: 12000, ExifImageHeight: 3000}, want: true},
+		{name: "Rotated panorama is tall", exif: ExifInfo{ExifImageWidth: 12000, ExifImageHeight: 3000, Orientation: "6"}, want: false},
+		{name: "Projection type", exif: ExifInfo{ExifImageWidth: 4000, ExifImageHeight: 3000, ProjectionType: "EQUIRECTANGULAR"}, want: true},
+		{name: "No dimensions", exif: ExifInfo{}, want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			asset := ImmichAsset{ExifInfo: tt.exif}
+			assert.Equal(t, tt.want, asset.IsPanorama(2.5))
+		})
+	}
+}
+
+// TestRendition tests the rendition picked for different clients
+func TestRendition(t *testing.T) {
+	jpeg := ImmichAsset{OriginalMimeType: "image/jpeg"}
+	heic := ImmichAsset{OriginalMimeType: "image/heic"}
+	small := ImmichAsset{OriginalMimeType: "image/jpeg", ExifInfo: ExifInfo{ExifImageWidth: 1200, ExifImageHeight: 800}}
+
+	tests := []struct {
+		name   string
+		asset  ImmichAsset
+		config config.Config
+		want   string
+	}{
+		{name: "No client data", asset: jpeg, config: config.Config{}, want: RenditionPreview},
+		{name: "Small e-ink frame", asset: jpeg, config: config.Config{ClientData: config.ClientData{Width: 200, Height: 200}}, want: RenditionThumbnail},
+		{name: "7 inch frame", asset: jpeg, config: config.Config{ClientData: config.ClientData{Width: 1024, Height: 600}}, want: RenditionPreview},
+		{name: "High DPR tablet", asset: jpeg, config: config.Config{ClientData: config.ClientData{Width: 1024, Height: 768, DevicePixelRatio: 2}}, want: RenditionFullsize},
+		{name: "1080p screen", asset: jpeg, config: config.Config{ClientData: config.ClientData{Width: 1920, Height: 1080}}, want: RenditionFullsize},
+		{name: "4K TV with unsupported original", asset: heic, config: config.Config{ClientData: config.ClientData{Width: 3840, Height: 2160}}, want: RenditionFullsize},
+		{name: "Image smaller than the screen", asset: small, config: config.Config{ClientData: config.ClientData{Width: 1920, Height: 1080}}, want: RenditionPreview},
+		{name: "Use original", asset: jpeg, config: config.Config{UseOriginalImage: true}, want: RenditionOriginal},
+		{name: "Use original with unsupported mime", asset: heic, config: config.Config{UseOriginalImage: true}, want: RenditionPreview},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			asset := tt.asset
+			asset.requestConfig = tt.config
+			assert.Equal(t, tt.want, asset.Rendition())
+		})
+	}
+}
+
+// TestRequestConfigPerAsset tests each asset keeps the config of its own request,
+// so concurrent requests from different devices do not pick up each other's settings
+func TestRequestConfigPerAsset(t *testing.T) {
+	originalConfig := requestConfig
+	t.Cleanup(func() { requestConfig = originalConfig })
+
+	eink := NewImage(config.Config{ClientData: config.ClientData{Width: 200, Height: 200}})
+	tv := NewImage(config.Config{ClientData: config.ClientData{Width: 3840, Height: 2160}})
+
+	eink.replace(ImmichAsset{ID: "asset-1", OriginalMimeType: "image/jpeg"})
+
+	assert.Equal(t, "asset-1", eink.ID)
+	assert.Equal(t, RenditionThumbnail, eink.Rendition(), "the asset should keep its request's config when replaced")
+	assert.Equal(t, RenditionFullsize, tv.Rendition())
+}
+
+// TestRecentApiErrors tests failed calls are kept newest first, without queries, up to the limit
+func TestRecentApiErrors(t *testing.T) {
+	apiErrors = nil
+	t.Cleanup(func() { apiErrors = nil })
+
+	recordApiError("GET", "https://immich.example.com/api/assets/1?size=preview", errors.New("unexpected status code: 500"))
+
+	recent := RecentApiErrors()
+	assert.Len(t, recent, 1)
+	assert.Equal(t, "/api/assets/1", recent[0].Path, "queries should be left out")
+	assert.Equal(t, "unexpected status code: 500", recent[0].Error)
+
+	for i := range maxApiErrors + 5 {
+		recordApiError("POST", fmt.Sprintf("https://immich.example.com/api/search/%d", i), errors.New("timeout"))
+	}
+
+	recent = RecentApiErrors()
+	assert.Len(t, recent, maxApiErrors, "only the most recent errors should be kept")
+	assert.Equal(t, fmt.Sprintf("/api/search/%d", maxApiErrors+4), recent[0].Path, "newest error should be first")
+}
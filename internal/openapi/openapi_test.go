@@ -0,0 +1,97 @@
+package openapi
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type testAsset struct {
+	ID       string         `json:"id"`
+	TakenAt  time.Time      `json:"takenAt"`
+	People   []string       `json:"people,omitempty"`
+	Extra    map[string]int `json:"extra"`
+	Location testLocation   `json:"location"`
+	Related  []testAsset    `json:"related"`
+	Hidden   string         `json:"-"`
+	Untagged float64
+	private  bool
+}
+
+type testLocation struct {
+	City string `json:"city"`
+}
+
+type testSettings struct {
+	Layout string   `query:"layout"`
+	Album  []string `query:"album"`
+	Client testClient
+	Secret string
+}
+
+type testClient struct {
+	Width int `query:"client_width"`
+}
+
+// TestPath tests echo path params are converted to OpenAPI path params
+func TestPath(t *testing.T) {
+	assert.Equal(t, "/devices/{id}/{command}", Path("/devices/:id/:command"))
+	assert.Equal(t, "/image", Path("/image"))
+}
+
+// TestAdd tests operations are added by method and undescribed path params are added to them
+func TestAdd(t *testing.T) {
+	doc := New(Info{Title: "test"})
+
+	doc.Add(http.MethodPost, "/devices/:id/:command", Operation{
+		OperationID: "command",
+		Parameters:  []Parameter{{Name: "command", In: "path", Required: true, Schema: &Schema{Type: "string", Enum: []string{"next"}}}},
+	})
+
+	op, ok := doc.Operation(http.MethodPost, "/devices/{id}/{command}")
+	assert.True(t, ok)
+	assert.Len(t, op.Parameters, 2)
+	assert.Equal(t, []string{"next"}, op.Parameters[0].Schema.Enum, "described path params should be kept")
+	assert.Equal(t, "id", op.Parameters[1].Name)
+	assert.True(t, op.Parameters[1].Required)
+
+	_, ok = doc.Operation(http.MethodGet, "/devices/{id}/{command}")
+	assert.False(t, ok)
+}
+
+// TestSchema tests schemas are generated from json tags and structs are referenced from the components
+func TestSchema(t *testing.T) {
+	doc := New(Info{Title: "test"})
+
+	assert.Equal(t, "#/components/schemas/testAsset", doc.Schema(testAsset{}).Ref)
+
+	asset := doc.Components.Schemas["testAsset"]
+	assert.Equal(t, &Schema{Type: "string"}, asset.Properties["id"])
+	assert.Equal(t, &Schema{Type: "string", Format: "date-time"}, asset.Properties["takenAt"])
+	assert.Equal(t, &Schema{Type: "array", Items: &Schema{Type: "string"}}, asset.Properties["people"])
+	assert.Equal(t, &Schema{Type: "object", AdditionalProperties: &Schema{Type: "integer"}}, asset.Properties["extra"])
+	assert.Equal(t, "#/components/schemas/testLocation", asset.Properties["location"].Ref)
+	assert.Equal(t, "#/components/schemas/testAsset", asset.Properties["related"].Items.Ref, "types that refer to themselves should be referenced")
+	assert.Equal(t, &Schema{Type: "number"}, asset.Properties["Untagged"])
+	assert.Len(t, asset.Properties, 7, "ignored and unexported fields should be skipped")
+
+	assert.Contains(t, doc.Components.Schemas, "testLocation")
+}
+
+// TestQueryParameters tests a parameter is added for each field with a query tag, including in nested structs
+func TestQueryParameters(t *testing.T) {
+	doc := New(Info{Title: "test"})
+
+	refs := doc.QueryParameters(&testSettings{})
+
+	assert.Equal(t, []Parameter{
+		{Ref: "#/components/parameters/layout"},
+		{Ref: "#/components/parameters/album"},
+		{Ref: "#/components/parameters/client_width"},
+	}, refs)
+
+	assert.Equal(t, Parameter{Name: "album", In: "query", Schema: &Schema{Type: "array", Items: &Schema{Type: "string"}}}, doc.Components.Parameters["album"])
+	assert.Equal(t, "integer", doc.Components.Parameters["client_width"].Schema.Type)
+}
@@ -26,8 +26,6 @@ const (
 
 var (
 	KioskVersion string
-
-	drawFacesOnImages string
 )
 
 type PersonOrAlbum struct {
@@ -35,10 +33,6 @@ type PersonOrAlbum struct {
 	ID   string
 }
 
-func ShouldDrawFacesOnImages() bool {
-	return drawFacesOnImages == "true"
-}
-
 // InitializeRequestData processes incoming request context and configuration to create RouteRequestData.
 // It handles kiosk version checks, client configuration overrides, and request metadata.
 //
@@ -52,13 +46,19 @@ func ShouldDrawFacesOnImages() bool {
 func InitializeRequestData(c echo.Context, baseConfig *config.Config) (*common.RouteRequestData, error) {
 
 	kioskDeviceVersion := c.Request().Header.Get("kiosk-version")
-	deviceID := c.Request().Header.Get("kiosk-device-id")
+	deviceID := deviceIDFromRequest(c)
 	requestID := utils.ColorizeRequestId(c.Response().Header().Get(echo.HeaderXRequestID))
 	clientName := c.QueryParams().Get("client")
 	if clientName == "" {
 		clientName = c.FormValue("client")
 	}
 
+	// requests using a token bound to a client profile always use it
+	authClient, _ := c.Get(authClientKey).(string)
+	if authClient != "" {
+		clientName = authClient
+	}
+
 	// create a copy of the global config to use with this request
 	requestConfig := *baseConfig
 
@@ -77,12 +77,26 @@ func InitializeRequestData(c echo.Context, baseConfig *config.Config) (*common.R
 
 	queries := utils.MergeQueries(queryParams, formParam)
 
+	if authClient != "" {
+		queries.Set("client", authClient)
+	}
+
+	// devices bound to a client profile server-side use it unless their URL picks one
+	if binding, ok := requestConfig.DeviceBinding(deviceID); ok && clientName == "" && binding.Client != "" {
+		clientName = binding.Client
+		queries.Set("client", clientName)
+	}
+
 	err = requestConfig.ConfigWithOverrides(queries, c)
 	if err != nil {
 		log.Error("initialise request data", "error", err, "path", c.Request().URL.Path)
 		return nil, echo.NewHTTPError(http.StatusInternalServerError, "Failed to process request")
 	}
 
+	if deviceID != "" {
+		registerDevice(c, deviceID, clientName, requestConfig)
+	}
+
 	return &common.RouteRequestData{
 		RequestConfig: requestConfig,
 		DeviceID:      deviceID,
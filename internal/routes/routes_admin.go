@@ -0,0 +1,188 @@
+package routes
+
+import (
+	"maps"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/log"
+	"github.com/labstack/echo/v4"
+	"github.com/labstack/echo/v4/middleware"
+
+	"github.com/damongolding/immich-kiosk/internal/auth"
+	"github.com/damongolding/immich-kiosk/internal/cache"
+	"github.com/damongolding/immich-kiosk/internal/common"
+	"github.com/damongolding/immich-kiosk/internal/config"
+	"github.com/damongolding/immich-kiosk/internal/devices"
+	"github.com/damongolding/immich-kiosk/internal/immich"
+	"github.com/damongolding/immich-kiosk/internal/templates/views"
+	"github.com/damongolding/immich-kiosk/internal/utils"
+	"github.com/damongolding/immich-kiosk/internal/weather"
+	"github.com/damongolding/immich-kiosk/internal/webhooks"
+)
+
+// adminMessages confirms the action the dashboard was redirected back from
+var adminMessages = map[string]string{
+	"flush":  "Cache flushed",
+	"next":   "Next image sent to device",
+	"reload": "Reload sent to device",
+	"revoke": "Token revoked",
+}
+
+// Admin renders the admin dashboard
+func Admin(baseConfig *config.Config) echo.HandlerFunc {
+	return func(c echo.Context) error {
+
+		requestID := utils.ColorizeRequestId(c.Response().Header().Get(echo.HeaderXRequestID))
+
+		log.Debug(
+			requestID,
+			"method", c.Request().Method,
+			"path", c.Request().URL.String(),
+		)
+
+		return renderAdmin(c, baseConfig, adminMessages[c.QueryParam("done")], "")
+	}
+}
+
+// renderAdmin renders the admin dashboard with a message and, right after it is created, a new token
+func renderAdmin(c echo.Context, baseConfig *config.Config, message, newToken string) error {
+	requestConfig := *baseConfig
+
+	csrfToken, _ := c.Get(middleware.DefaultCSRFConfig.ContextKey).(string)
+
+	clients := slices.Collect(maps.Keys(requestConfig.Clients))
+	slices.Sort(clients)
+
+	return Render(c, http.StatusOK, views.Admin(common.AdminViewData{
+		KioskVersion:    KioskVersion,
+		CSRFToken:       csrfToken,
+		Message:         message,
+		Now:             time.Now(),
+		Devices:         devices.All(),
+		Cache:           cache.GetStats(),
+		Weather:         weather.Locations(),
+		ApiErrors:       immich.RecentApiErrors(),
+		ReloadTimeStamp: requestConfig.ReloadTimeStamp,
+		Tokens:          auth.Tokens(),
+		NewToken:        newToken,
+		Clients:         clients,
+	}))
+}
+
+// AdminFlushCache flushes the cache from the admin dashboard
+func AdminFlushCache(baseConfig *config.Config) echo.HandlerFunc {
+	return func(c echo.Context) error {
+
+		requestData := &common.RouteRequestData{
+			RequestConfig: *baseConfig,
+			RequestID:     utils.ColorizeRequestId(c.Response().Header().Get(echo.HeaderXRequestID)),
+		}
+
+		log.Info("Cache flushed from admin", "cache_items", cache.ItemCount())
+
+		cache.Flush()
+
+		go webhooks.Trigger(requestData, KioskVersion, webhooks.CacheFlush, common.ViewData{})
+		return redirectToAdmin(c, "flush")
+	}
+}
+
+// AdminDeviceCommand sends a command to a device from the admin dashboard.
+// The command is delivered the next time the device checks in.
+func AdminDeviceCommand(c echo.Context) error {
+	deviceID := c.Param("id")
+	command := devices.Command{Action: devices.Action(c.Param("command"))}
+
+	switch command.Action {
+	case devices.ActionNext, devices.ActionReload:
+	default:
+		return echo.NewHTTPError(http.StatusBadRequest, "Unknown command")
+	}
+
+	if !devices.SendCommand(deviceID, command) {
+		return echo.NewHTTPError(http.StatusNotFound, "Unknown device")
+	}
+
+	log.Info("Sent command to device", "device", deviceID, "command", command.Action)
+
+	return redirectToAdmin(c, string(command.Action))
+}
+
+// AdminCreateToken creates an API token from the admin dashboard, optionally bound to a client profile and device.
+// The dashboard is rendered rather than redirected to as the token is only shown this once.
+func AdminCreateToken(baseConfig *config.Config) echo.HandlerFunc {
+	return func(c echo.Context) error {
+
+		name := strings.TrimSpace(c.FormValue("name"))
+		client := c.FormValue("client")
+		deviceID := c.FormValue("device")
+
+		if name == "" {
+			return echo.NewHTTPError(http.StatusBadRequest, "Token name is required")
+		}
+
+		if _, ok := baseConfig.ClientProfile(client); client != "" && !ok {
+			return echo.NewHTTPError(http.StatusBadRequest, "Unknown client")
+		}
+
+		if _, ok := devices.Get(deviceID); deviceID != "" && !ok {
+			return echo.NewHTTPError(http.StatusBadRequest, "Unknown device")
+		}
+
+		token, created, err := auth.CreateToken(name, client, deviceID)
+		if err != nil {
+			log.Error("creating token", "err", err)
+			return echo.NewHTTPError(http.StatusInternalServerError, "Failed to create token")
+		}
+
+		log.Info("Token created from admin", "token", created.Name, "client", created.Client, "device", created.DeviceID)
+
+		return renderAdmin(c, baseConfig, "Token created. Copy it now, it will not be shown again", token)
+	}
+}
+
+// AdminRevokeToken revokes an API token from the admin dashboard, ending any sessions started with it
+func AdminRevokeToken(c echo.Context) error {
+	tokenID := c.Param("id")
+
+	token, ok := auth.GetToken(tokenID)
+	if !ok || !auth.RevokeToken(tokenID) {
+		return echo.NewHTTPError(http.StatusNotFound, "Unknown token")
+	}
+
+	log.Info("Token revoked from admin", "token", token.Name)
+
+	return redirectToAdmin(c, "revoke")
+}
+
+// AdminAssetThumbnail serves the thumbnail of an asset so the dashboard can show what each device is showing
+func AdminAssetThumbnail(baseConfig *config.Config) echo.HandlerFunc {
+	return func(c echo.Context) error {
+
+		assetID := c.Param("id")
+		if !utils.IsValidUUID(assetID) {
+			return echo.NewHTTPError(http.StatusBadRequest, "Invalid asset ID")
+		}
+
+		immichImage := immich.NewImage(*baseConfig)
+		immichImage.ID = assetID
+
+		imgBytes, err := immichImage.ImageRendition(immich.RenditionThumbnail)
+		if err != nil {
+			log.Error("fetching admin thumbnail", "id", assetID, "err", err)
+			return echo.NewHTTPError(http.StatusBadGateway, "Failed to fetch thumbnail")
+		}
+
+		c.Response().Header().Set("Cache-Control", "private, max-age=3600")
+		return c.Blob(http.StatusOK, http.DetectContentType(imgBytes), imgBytes)
+	}
+}
+
+// redirectToAdmin sends the browser back to the dashboard after an action, so refreshing the page does not repeat it
+func redirectToAdmin(c echo.Context, done string) error {
+	return c.Redirect(http.StatusSeeOther, "/admin?"+url.Values{"done": {done}}.Encode())
+}
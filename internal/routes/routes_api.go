@@ -0,0 +1,211 @@
+package routes
+
+import (
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/log"
+	"github.com/labstack/echo/v4"
+
+	"github.com/damongolding/immich-kiosk/internal/common"
+	"github.com/damongolding/immich-kiosk/internal/config"
+	"github.com/damongolding/immich-kiosk/internal/devices"
+	"github.com/damongolding/immich-kiosk/internal/immich"
+	"github.com/damongolding/immich-kiosk/internal/kiosk"
+	"github.com/damongolding/immich-kiosk/internal/utils"
+	"github.com/damongolding/immich-kiosk/internal/webhooks"
+)
+
+// apiHistoryLength is how many history entries are returned to API clients
+const apiHistoryLength = 10
+
+// ApiResponse is returned by the next and previous asset API endpoints
+type ApiResponse struct {
+	// Assets the assets to show, more than one when the layout shows several side by side
+	Assets []ApiAsset `json:"assets"`
+	// History the entries to send back as history query params to go to previous assets
+	History []string `json:"history"`
+}
+
+// ApiAsset is the metadata and images of an asset for API clients
+type ApiAsset struct {
+	ID            string                 `json:"id"`
+	Type          immich.ImmichAssetType `json:"type"`
+	TakenAt       time.Time              `json:"takenAt"`
+	LocalDateTime time.Time              `json:"localDateTime"`
+	Description   string                 `json:"description,omitempty"`
+	Location      ApiLocation            `json:"location"`
+	People        []string               `json:"people"`
+	Album         string                 `json:"album,omitempty"`
+	Source        kiosk.Source           `json:"source"`
+	SourceName    string                 `json:"sourceName,omitempty"`
+	// ImageURL the processed image as a data URL
+	ImageURL string `json:"imageUrl"`
+	// BlurURL the blurred background image as a data URL, empty when background blur is disabled
+	BlurURL string `json:"blurUrl,omitempty"`
+	// ImmichURL the link to the asset in Immich
+	ImmichURL string `json:"immichUrl"`
+	// QrCode a QR code of the Immich link as a data URL
+	QrCode string `json:"qrCode"`
+}
+
+// ApiLocation is where an asset was taken
+type ApiLocation struct {
+	City      string  `json:"city,omitempty"`
+	State     string  `json:"state,omitempty"`
+	Country   string  `json:"country,omitempty"`
+	Latitude  float64 `json:"latitude,omitempty"`
+	Longitude float64 `json:"longitude,omitempty"`
+}
+
+// ApiNext returns an echo.HandlerFunc that returns the next assets as JSON.
+// The assets are picked and processed the same way as the images the kiosk shows.
+func ApiNext(baseConfig *config.Config) echo.HandlerFunc {
+	return func(c echo.Context) error {
+
+		requestData, err := apiRequestData(c, baseConfig)
+		if err != nil || requestData == nil {
+			return err
+		}
+
+		requestConfig := requestData.RequestConfig
+		deviceID := requestData.DeviceID
+
+		if isSleepMode(requestConfig) || devices.IsSleeping(deviceID) {
+			return c.NoContent(http.StatusNoContent)
+		}
+
+		viewData, err := generateViewData(requestConfig, c, deviceID, false)
+		if err != nil {
+			log.Error("api next asset", "err", err)
+			return echo.NewHTTPError(http.StatusInternalServerError, "Failed to retrieve asset")
+		}
+
+		go webhooks.Trigger(requestData, KioskVersion, webhooks.NewAsset, viewData)
+		recordCurrentAssets(deviceID, viewData)
+		return c.JSON(http.StatusOK, newApiResponse(viewData, requestConfig.History))
+	}
+}
+
+// ApiPrevious returns an echo.HandlerFunc that returns the previously shown assets as JSON.
+// The history returned by the last API call is sent back as history query params.
+func ApiPrevious(baseConfig *config.Config) echo.HandlerFunc {
+	return func(c echo.Context) error {
+
+		requestData, err := apiRequestData(c, baseConfig)
+		if err != nil || requestData == nil {
+			return err
+		}
+
+		requestConfig := requestData.RequestConfig
+		requestID := requestData.RequestID
+		deviceID := requestData.DeviceID
+
+		historyLen := len(requestConfig.History)
+
+		if isSleepMode(requestConfig) || devices.IsSleeping(deviceID) || historyLen < 2 {
+			return c.NoContent(http.StatusNoContent)
+		}
+
+		prevAssets := strings.Split(requestConfig.History[historyLen-2], ",")
+		requestConfig.History = requestConfig.History[:historyLen-2]
+
+		viewData, err := previousViewData(c, requestConfig, requestID, deviceID, prevAssets)
+		if err != nil {
+			log.Error("api previous asset", "err", err)
+			return echo.NewHTTPError(http.StatusInternalServerError, "Failed to retrieve asset")
+		}
+
+		go webhooks.Trigger(requestData, KioskVersion, webhooks.PreviousAsset, viewData)
+		recordCurrentAssets(deviceID, viewData)
+		return c.JSON(http.StatusOK, newApiResponse(viewData, requestConfig.History))
+	}
+}
+
+// apiRequestData initializes the request data for an API request.
+// History is read from the query as API clients make GET requests.
+func apiRequestData(c echo.Context, baseConfig *config.Config) (*common.RouteRequestData, error) {
+	requestData, err := InitializeRequestData(c, baseConfig)
+	if err != nil || requestData == nil {
+		return nil, err
+	}
+
+	if history := c.QueryParams()["history"]; len(requestData.RequestConfig.History) == 0 && len(history) > 0 {
+		requestData.RequestConfig.History = history
+	}
+
+	log.Debug(
+		requestData.RequestID,
+		"method", c.Request().Method,
+		"deviceID", requestData.DeviceID,
+		"path", c.Request().URL.String(),
+		"requestConfig", requestData.RequestConfig.String(),
+	)
+
+	return requestData, nil
+}
+
+// newApiResponse converts view data into an API response.
+// The returned history is the given history followed by an entry for the assets in viewData.
+func newApiResponse(viewData common.ViewData, history []string) ApiResponse {
+	res := ApiResponse{
+		Assets: make([]ApiAsset, 0, len(viewData.Images)),
+	}
+
+	assetIDs := make([]string, 0, len(viewData.Images))
+
+	for _, image := range viewData.Images {
+		res.Assets = append(res.Assets, newApiAsset(image, viewData.Config))
+		assetIDs = append(assetIDs, image.ImmichImage.ID)
+	}
+
+	res.History = append(slices.Clone(history), strings.Join(assetIDs, ","))
+	trimHistory(&res.History, apiHistoryLength)
+
+	return res
+}
+
+// newApiAsset converts an asset's view data into its API representation.
+func newApiAsset(image common.ViewImageData, requestConfig config.Config) ApiAsset {
+	asset := image.ImmichImage
+	immichURL := utils.ImmichAssetURL(requestConfig.ImmichUrl, requestConfig.ImmichExternalUrl, asset.ID)
+
+	apiAsset := ApiAsset{
+		ID:            asset.ID,
+		Type:          asset.Type,
+		TakenAt:       asset.ExifInfo.DateTimeOriginal,
+		LocalDateTime: asset.LocalDateTime,
+		Description:   asset.ExifInfo.Description,
+		Location: ApiLocation{
+			City:      asset.ExifInfo.City,
+			State:     asset.ExifInfo.State,
+			Country:   asset.ExifInfo.Country,
+			Latitude:  asset.ExifInfo.Latitude,
+			Longitude: asset.ExifInfo.Longitude,
+		},
+		People:     make([]string, 0, len(asset.People)),
+		Source:     asset.KioskSource,
+		SourceName: asset.KioskSourceName,
+		ImageURL:   image.ImageData,
+		BlurURL:    image.ImageBlurData,
+		ImmichURL:  immichURL,
+	}
+
+	if asset.KioskSource == kiosk.SourceAlbums || asset.KioskSource == kiosk.SourceDateRangeAlbum {
+		apiAsset.Album = asset.KioskSourceName
+	}
+
+	for _, person := range asset.People {
+		if person.Name != "" {
+			apiAsset.People = append(apiAsset.People, person.Name)
+		}
+	}
+
+	if immichURL != "#" {
+		apiAsset.QrCode = utils.CreateQrCode(immichURL)
+	}
+
+	return apiAsset
+}
@@ -0,0 +1,368 @@
+package routes
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/charmbracelet/log"
+	"github.com/labstack/echo/v4"
+	"github.com/labstack/echo/v4/middleware"
+	"golang.org/x/time/rate"
+
+	"github.com/damongolding/immich-kiosk/internal/auth"
+	"github.com/damongolding/immich-kiosk/internal/common"
+	"github.com/damongolding/immich-kiosk/internal/config"
+	"github.com/damongolding/immich-kiosk/internal/templates/views"
+)
+
+const (
+	// sessionCookie holds the session started by the login page
+	sessionCookie = "kiosk_session"
+
+	// authClientKey and authDeviceKey hold the client profile and device the request's token is bound to
+	authClientKey = "kiosk-auth-client"
+	authDeviceKey = "kiosk-auth-device"
+
+	adminUsername = "admin"
+	adminRealm    = "Immich Kiosk admin"
+
+	// failedAuthBurst is how many failed credential checks an IP can make before it is throttled,
+	// after which it can make one every failedAuthInterval
+	failedAuthBurst    = 10
+	failedAuthInterval = 6 * time.Second
+)
+
+// failedAuth limits failed credential checks per IP. Checking a password or token against its bcrypt hash is
+// deliberately slow, so without a limit anyone could keep Kiosk busy by sending wrong passwords.
+var failedAuth = struct {
+	sync.Mutex
+	clients map[string]*rate.Limiter
+}{clients: make(map[string]*rate.Limiter)}
+
+// kioskCredential returns the password for the slideshow and API
+func kioskCredential(baseConfig *config.Config) auth.Credential {
+	return auth.Credential{Secret: baseConfig.Kiosk.Password, Hash: baseConfig.Kiosk.PasswordHash}
+}
+
+// adminCredential returns the password for the admin dashboard and remote control API
+func adminCredential(baseConfig *config.Config) auth.Credential {
+	return auth.Credential{Secret: baseConfig.Kiosk.AdminPassword, Hash: baseConfig.Kiosk.AdminPasswordHash}
+}
+
+// authEnabled reports whether requests must be authenticated
+func authEnabled(baseConfig *config.Config) bool {
+	return kioskCredential(baseConfig).IsSet() || auth.HasTokens()
+}
+
+// AdminEnabled reports whether an admin password is set, enabling the admin dashboard and remote control API
+func AdminEnabled(baseConfig *config.Config) bool {
+	return adminCredential(baseConfig).IsSet()
+}
+
+// Authenticate returns middleware that only lets authenticated requests through.
+// Requests are authenticated by a session cookie from the login page, a token (as a token param or bearer token),
+// the password (as a password param) or the admin credentials. Requests using a token are bound to its client profile and device.
+// Nothing is checked until a password is set or a token is created.
+// IPs that keep sending wrong credentials are throttled.
+func Authenticate(baseConfig *config.Config) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			if !authEnabled(baseConfig) || isPublicRoute(c.Path()) {
+				return next(c)
+			}
+
+			now := time.Now()
+			ip := c.RealIP()
+
+			if authThrottled(ip, now) {
+				return echo.ErrTooManyRequests
+			}
+
+			if !authenticated(c, baseConfig, now) {
+				if hasCredentials(c) {
+					recordFailedAuth(ip, now)
+				}
+				return unauthorized(c)
+			}
+
+			return next(c)
+		}
+	}
+}
+
+// isPublicRoute reports whether the route can be requested without authenticating.
+// Routes are matched rather than the request path so paths that fall through to the redirects are not public.
+// The admin dashboard is not public but has its own authentication.
+func isPublicRoute(route string) bool {
+	return strings.HasPrefix(route, "/assets") ||
+		route == "/admin" ||
+		strings.HasPrefix(route, "/admin/") ||
+		route == "/login" ||
+		route == "/logout"
+}
+
+// authThrottled reports whether ip has made too many failed credential checks to be checked again yet
+func authThrottled(ip string, now time.Time) bool {
+	failedAuth.Lock()
+	defer failedAuth.Unlock()
+
+	limiter, ok := failedAuth.clients[ip]
+	return ok && limiter.TokensAt(now) < 1
+}
+
+// recordFailedAuth records a failed credential check from ip, forgetting IPs that have not failed for a while
+func recordFailedAuth(ip string, now time.Time) {
+	failedAuth.Lock()
+	defer failedAuth.Unlock()
+
+	for client, limiter := range failedAuth.clients {
+		if limiter.TokensAt(now) >= failedAuthBurst {
+			delete(failedAuth.clients, client)
+		}
+	}
+
+	limiter, ok := failedAuth.clients[ip]
+	if !ok {
+		limiter = rate.NewLimiter(rate.Every(failedAuthInterval), failedAuthBurst)
+		failedAuth.clients[ip] = limiter
+	}
+
+	limiter.AllowN(now, 1)
+}
+
+// authenticated reports whether the request carries valid credentials, binding it to its token's client profile and device.
+func authenticated(c echo.Context, baseConfig *config.Config, now time.Time) bool {
+	if cookie, err := c.Cookie(sessionCookie); err == nil {
+		if session, ok := auth.ValidSession(cookie.Value, kioskCredential(baseConfig), now); ok {
+			if token, ok := auth.GetToken(session.TokenID); ok {
+				bindToken(c, token)
+			}
+			return true
+		}
+	}
+
+	if given := requestToken(c); given != "" {
+		if token, ok := auth.VerifyToken(given, now); ok {
+			bindToken(c, token)
+			return true
+		}
+	}
+
+	if password := requestParam(c, "password"); password != "" && kioskCredential(baseConfig).Check(password) {
+		return true
+	}
+
+	if username, password, ok := c.Request().BasicAuth(); ok && isAdmin(username, password, baseConfig) {
+		return true
+	}
+
+	return false
+}
+
+// hasCredentials reports whether the request carries any credentials to check
+func hasCredentials(c echo.Context) bool {
+	if _, err := c.Cookie(sessionCookie); err == nil {
+		return true
+	}
+
+	_, _, basicAuth := c.Request().BasicAuth()
+
+	return basicAuth || requestToken(c) != "" || requestParam(c, "password") != ""
+}
+
+// bindToken binds the request to the client profile and device its token is for
+func bindToken(c echo.Context, token auth.Token) {
+	if token.Client != "" {
+		c.Set(authClientKey, token.Client)
+	}
+	if token.DeviceID != "" {
+		c.Set(authDeviceKey, token.DeviceID)
+	}
+}
+
+// requestToken returns the token sent as a bearer token or a token param
+func requestToken(c echo.Context) string {
+	if scheme, token, ok := strings.Cut(c.Request().Header.Get(echo.HeaderAuthorization), " "); ok && strings.EqualFold(scheme, "bearer") {
+		return strings.TrimSpace(token)
+	}
+
+	return requestParam(c, "token")
+}
+
+// requestParam returns the named query param, or form value if it is not in the query
+func requestParam(c echo.Context, name string) string {
+	if value := c.QueryParam(name); value != "" {
+		return value
+	}
+
+	return c.FormValue(name)
+}
+
+// unauthorized responds to a request without valid credentials.
+// Pages opened in a browser are sent to the login page, along with htmx requests from pages whose login has ended.
+func unauthorized(c echo.Context) error {
+	req := c.Request()
+
+	if req.Header.Get("HX-Request") == "true" {
+		target := "/"
+		if current, err := url.Parse(req.Header.Get("HX-Current-URL")); err == nil {
+			target = current.RequestURI()
+		}
+		c.Response().Header().Set("HX-Redirect", loginURL(target))
+		return c.String(http.StatusUnauthorized, "Unauthorized")
+	}
+
+	if req.Method == http.MethodGet && strings.Contains(req.Header.Get(echo.HeaderAccept), echo.MIMETextHTML) {
+		return c.Redirect(http.StatusSeeOther, loginURL(req.URL.RequestURI()))
+	}
+
+	return c.String(http.StatusUnauthorized, "Unauthorized")
+}
+
+// loginURL returns the login page URL that sends the browser on to target once logged in
+func loginURL(target string) string {
+	return "/login?" + url.Values{"redirect": {safeRedirect(target)}}.Encode()
+}
+
+// safeRedirect returns target if it is a path on this server, otherwise the slideshow,
+// so the login page can not be used to send people to other sites.
+func safeRedirect(target string) string {
+	if !strings.HasPrefix(target, "/") || strings.HasPrefix(target, "//") || strings.HasPrefix(target, "/\\") {
+		return "/"
+	}
+
+	u, err := url.Parse(target)
+	if err != nil || u.Scheme != "" || u.Host != "" || u.Path == "/login" {
+		return "/"
+	}
+
+	return target
+}
+
+// isAdmin reports whether the username and password are the admin credentials
+func isAdmin(username, password string, baseConfig *config.Config) bool {
+	usernameMatch := subtle.ConstantTimeCompare([]byte(username), []byte(adminUsername)) == 1
+	passwordMatch := adminCredential(baseConfig).Check(password)
+	return usernameMatch && passwordMatch
+}
+
+// RequireAdmin returns middleware that only lets requests with the admin credentials through, using HTTP basic auth.
+// Requests are forbidden until an admin password is set.
+func RequireAdmin(baseConfig *config.Config) echo.MiddlewareFunc {
+	basicAuth := middleware.BasicAuthWithConfig(middleware.BasicAuthConfig{
+		Realm: adminRealm,
+		Validator: func(username, password string, c echo.Context) (bool, error) {
+			return isAdmin(username, password, baseConfig), nil
+		},
+	})
+
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		withBasicAuth := basicAuth(next)
+
+		return func(c echo.Context) error {
+			if !AdminEnabled(baseConfig) {
+				return echo.NewHTTPError(http.StatusForbidden, "Set admin_password to use this endpoint")
+			}
+
+			return withBasicAuth(c)
+		}
+	}
+}
+
+// Login renders the login page
+func Login(baseConfig *config.Config) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		redirect := safeRedirect(c.QueryParam("redirect"))
+
+		if !authEnabled(baseConfig) {
+			return c.Redirect(http.StatusSeeOther, redirect)
+		}
+
+		loggedIn := false
+		if cookie, err := c.Cookie(sessionCookie); err == nil {
+			_, loggedIn = auth.ValidSession(cookie.Value, kioskCredential(baseConfig), time.Now())
+		}
+
+		return renderLogin(c, http.StatusOK, redirect, "", loggedIn)
+	}
+}
+
+// LoginSubmit checks the password or token from the login page and starts a session for the browser
+func LoginSubmit(baseConfig *config.Config) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		secret := c.FormValue("secret")
+		redirect := safeRedirect(c.FormValue("redirect"))
+		now := time.Now()
+		ip := c.RealIP()
+
+		if authThrottled(ip, now) {
+			return renderLogin(c, http.StatusTooManyRequests, redirect, "Too many failed attempts, try again later", false)
+		}
+
+		var sessionID string
+		var err error
+
+		if token, ok := auth.VerifyToken(secret, now); ok {
+			sessionID, err = auth.NewTokenSession(token.ID, now)
+			log.Info("Logged in", "token", token.Name, "ip", c.RealIP())
+		} else if password := kioskCredential(baseConfig); password.Check(secret) {
+			sessionID, err = auth.NewPasswordSession(password, now)
+			log.Info("Logged in", "ip", c.RealIP())
+		} else {
+			log.Warn("Failed login", "ip", ip)
+			recordFailedAuth(ip, now)
+			return renderLogin(c, http.StatusUnauthorized, redirect, "Incorrect password or token", false)
+		}
+
+		if err != nil {
+			log.Error("starting session", "err", err)
+			return echo.NewHTTPError(http.StatusInternalServerError, "Failed to log in")
+		}
+
+		c.SetCookie(&http.Cookie{
+			Name:     sessionCookie,
+			Value:    sessionID,
+			Path:     "/",
+			MaxAge:   int(auth.SessionTTL.Seconds()),
+			Secure:   c.Scheme() == "https",
+			HttpOnly: true,
+			SameSite: http.SameSiteLaxMode,
+		})
+
+		return c.Redirect(http.StatusSeeOther, redirect)
+	}
+}
+
+// Logout ends the browser's session. It is posted from the login page.
+func Logout(c echo.Context) error {
+	if cookie, err := c.Cookie(sessionCookie); err == nil {
+		auth.EndSession(cookie.Value)
+	}
+
+	c.SetCookie(&http.Cookie{
+		Name:     sessionCookie,
+		Path:     "/",
+		MaxAge:   -1,
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+	})
+
+	return c.Redirect(http.StatusSeeOther, "/login")
+}
+
+// renderLogin renders the login page with an optional error, and the logout form when the browser is logged in
+func renderLogin(c echo.Context, status int, redirect, loginError string, loggedIn bool) error {
+	csrfToken, _ := c.Get(middleware.DefaultCSRFConfig.ContextKey).(string)
+
+	return Render(c, status, views.Login(common.LoginViewData{
+		KioskVersion: KioskVersion,
+		CSRFToken:    csrfToken,
+		Redirect:     redirect,
+		Error:        loginError,
+		LoggedIn:     loggedIn,
+	}))
+}
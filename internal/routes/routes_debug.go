@@ -0,0 +1,164 @@
+package routes
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+
+	"github.com/charmbracelet/log"
+	"github.com/fogleman/gg"
+
+	"github.com/damongolding/immich-kiosk/internal/config"
+	"github.com/damongolding/immich-kiosk/internal/immich"
+	"github.com/damongolding/immich-kiosk/internal/utils"
+)
+
+var (
+	debugPersonColor     = color.RGBA{R: 230, G: 57, B: 70, A: 255}
+	debugUnassignedColor = color.RGBA{R: 69, G: 123, B: 230, A: 255}
+	debugCenterColor     = color.RGBA{R: 240, G: 220, B: 40, A: 255}
+)
+
+// isDebugOverlayEnabled reports whether images should be annotated with the debug overlay.
+// The overlay shows people's names so it is only available in debug mode.
+func isDebugOverlayEnabled(requestConfig config.Config) bool {
+	return requestConfig.DebugOverlay && requestConfig.Kiosk.Debug
+}
+
+// debugOverlay returns the image annotated with the debug overlay when it is enabled,
+// fetching the asset's faces first if they have not been already.
+// The original image is left untouched so backgrounds are built without the overlay.
+func debugOverlay(img image.Image, immichImage *immich.ImmichAsset, requestConfig config.Config, requestID, deviceID string) image.Image {
+	if !isDebugOverlayEnabled(requestConfig) {
+		return img
+	}
+
+	if len(immichImage.People)+len(immichImage.UnassignedFaces) == 0 {
+		immichImage.CheckForFaces(requestID, deviceID)
+	}
+
+	return drawDebugOverlay(img, immichImage)
+}
+
+// drawDebugOverlay draws face boxes with person names, the smart-zoom center
+// and the selection details of the asset onto a copy of the image.
+func drawDebugOverlay(img image.Image, immichImage *immich.ImmichAsset) image.Image {
+	bounds := img.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+
+	dc := gg.NewContext(width, height)
+	dc.DrawImage(img, -bounds.Min.X, -bounds.Min.Y)
+
+	if err := loadFrameFonts(); err != nil {
+		log.Error("loading debug overlay fonts", "err", err)
+		return dc.Image()
+	}
+
+	baseSize := max(12, float64(height)/50)
+	lineWidth := max(2, float64(height)/400)
+	localBounds := image.Rect(0, 0, width, height)
+
+	drawFace := func(face immich.Face, label string, faceColor color.Color) {
+		x1, y1, x2, y2, ok := scaleFaceBox(face, localBounds)
+		if !ok {
+			return
+		}
+
+		dc.SetColor(faceColor)
+		dc.SetLineWidth(lineWidth)
+		dc.DrawRectangle(x1, y1, x2-x1, y2-y1)
+		dc.Stroke()
+
+		drawFrameTextBlock(dc, []frameTextLine{{Text: label, Scale: 0.8}}, baseSize, x1, y1-baseSize*0.75, 0, 1, "solid", color.White)
+	}
+
+	for _, person := range immichImage.People {
+		name := person.Name
+		if name == "" {
+			name = "unnamed"
+		}
+
+		for _, face := range person.Faces {
+			drawFace(face, name, debugPersonColor)
+		}
+	}
+
+	for _, face := range immichImage.UnassignedFaces {
+		drawFace(face, "unassigned", debugUnassignedColor)
+	}
+
+	if x, y := immichImage.FacesCenterPoint(); x != 0 || y != 0 {
+		cx, cy := x/100*float64(width), y/100*float64(height)
+		arm := baseSize
+
+		dc.SetColor(debugCenterColor)
+		dc.SetLineWidth(lineWidth)
+		dc.DrawLine(cx-arm, cy, cx+arm, cy)
+		dc.DrawLine(cx, cy-arm, cx, cy+arm)
+		dc.Stroke()
+		dc.DrawCircle(cx, cy, arm/2)
+		dc.Stroke()
+	}
+
+	details := debugOverlayLines(immichImage)
+	lines := make([]frameTextLine, 0, len(details))
+	for _, line := range details {
+		lines = append(lines, frameTextLine{Text: line, Scale: 0.8})
+	}
+
+	padding := baseSize * 1.5
+	drawFrameTextBlock(dc, lines, baseSize, padding, float64(height)-padding, 0, 1, "solid", color.White)
+
+	return dc.Image()
+}
+
+// debugOverlayLines returns the asset and selection details shown by the debug overlay.
+// Details that are unknown, e.g. for previous images which are not selected again, are left out.
+func debugOverlayLines(immichImage *immich.ImmichAsset) []string {
+	lines := []string{"asset " + immichImage.ID}
+
+	if immichImage.KioskSource != "" {
+		source := string(immichImage.KioskSource)
+		if immichImage.KioskSourceName != "" {
+			source += " (" + immichImage.KioskSourceName + ")"
+		}
+		lines = append(lines, "source "+source)
+	}
+
+	if immichImage.KioskTotalWeight > 0 {
+		lines = append(lines, fmt.Sprintf("weighting %d / %d (%.1f%%)",
+			immichImage.KioskSourceWeight, immichImage.KioskTotalWeight,
+			float64(immichImage.KioskSourceWeight)/float64(immichImage.KioskTotalWeight)*100,
+		))
+	}
+
+	if immichImage.KioskSelectionTime > 0 {
+		lines = append(lines, fmt.Sprintf("selected in %dms", immichImage.KioskSelectionTime.Milliseconds()))
+	}
+
+	faces := len(immichImage.UnassignedFaces)
+	for _, person := range immichImage.People {
+		faces += len(person.Faces)
+	}
+	lines = append(lines, fmt.Sprintf("faces %d (%d people)", faces, len(immichImage.People)))
+
+	if x, y := immichImage.FacesCenterPoint(); x != 0 || y != 0 {
+		lines = append(lines, fmt.Sprintf("smart-zoom center %.1f%%, %.1f%%", x, y))
+	}
+
+	return lines
+}
+
+// assetWeighting returns the weight of the bucket an asset was picked from and the total weight of all buckets.
+func assetWeighting(picked utils.WeightedAsset, assets []utils.AssetWithWeighting) (int, int) {
+	var weight, total int
+
+	for _, asset := range assets {
+		total += asset.Weight
+		if asset.Asset == picked {
+			weight = asset.Weight
+		}
+	}
+
+	return weight, total
+}
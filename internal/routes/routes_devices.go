@@ -0,0 +1,122 @@
+package routes
+
+import (
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+
+	"github.com/damongolding/immich-kiosk/internal/common"
+	"github.com/damongolding/immich-kiosk/internal/config"
+	"github.com/damongolding/immich-kiosk/internal/devices"
+	"github.com/damongolding/immich-kiosk/internal/utils"
+)
+
+const (
+	deviceIDHeader = "kiosk-device-id"
+	deviceIDCookie = "kiosk-device-id"
+	// deviceIDCookieMaxAge is the longest cookie lifetime browsers allow (400 days)
+	deviceIDCookieMaxAge = 400 * 24 * 60 * 60
+)
+
+// deviceIDFromRequest returns the device ID sent with the request, from the kiosk-device-id header
+// set by the frontend or, failing that, the device ID cookie. Invalid IDs are ignored.
+// Requests using a token bound to a device are always from that device.
+func deviceIDFromRequest(c echo.Context) string {
+	if id, ok := c.Get(authDeviceKey).(string); ok && devices.ValidID(id) {
+		return id
+	}
+
+	if id := c.Request().Header.Get(deviceIDHeader); devices.ValidID(id) {
+		return id
+	}
+
+	if cookie, err := c.Cookie(deviceIDCookie); err == nil && devices.ValidID(cookie.Value) {
+		return cookie.Value
+	}
+
+	return ""
+}
+
+// ensureDeviceID returns the device ID of the request, issuing a new one if the device does not have one yet
+// or sent an ID Kiosk does not know. Issued IDs are added to the device registry straight away.
+// The cookie is refreshed on every call so devices that are in use never lose their ID.
+func ensureDeviceID(c echo.Context, baseConfig *config.Config) (string, bool) {
+	id := deviceIDFromRequest(c)
+	isNew := id == "" || !knownDevice(c, id, baseConfig)
+
+	if isNew {
+		id = utils.GenerateUUID()
+		// later lookups in this request see the new ID
+		c.Request().Header.Set(deviceIDHeader, id)
+
+		devices.Seen(id, devices.Visit{
+			IP:        c.RealIP(),
+			UserAgent: c.Request().UserAgent(),
+		})
+	}
+
+	c.SetCookie(&http.Cookie{
+		Name:     deviceIDCookie,
+		Value:    id,
+		Path:     "/",
+		MaxAge:   deviceIDCookieMaxAge,
+		SameSite: http.SameSiteLaxMode,
+	})
+
+	return id, isNew
+}
+
+// knownDevice reports whether id is a device ID Kiosk issued, i.e. one in the device registry,
+// is bound in config.yaml or belongs to the token the request was authenticated with.
+// Any other ID a client sends is not trusted, so clients can not fill the registry with IDs of their own.
+func knownDevice(c echo.Context, id string, baseConfig *config.Config) bool {
+	if devices.Known(id) {
+		return true
+	}
+
+	if _, ok := baseConfig.DeviceBinding(id); ok {
+		return true
+	}
+
+	tokenDevice, _ := c.Get(authDeviceKey).(string)
+	return tokenDevice != "" && tokenDevice == id
+}
+
+// registerDevice records the request in the device registry.
+// clientName is the client profile the device is using, which can be empty if it is not using one.
+// Requests from devices that are not known (see knownDevice) are not recorded.
+func registerDevice(c echo.Context, deviceID, clientName string, requestConfig config.Config) {
+	if !knownDevice(c, deviceID, &requestConfig) {
+		return
+	}
+
+	binding, _ := requestConfig.DeviceBinding(deviceID)
+
+	if clientName == "" {
+		clientName = binding.Client
+	}
+
+	devices.Seen(deviceID, devices.Visit{
+		Name:             binding.Name,
+		Client:           clientName,
+		IP:               c.RealIP(),
+		UserAgent:        c.Request().UserAgent(),
+		Width:            requestConfig.ClientData.Width,
+		Height:           requestConfig.ClientData.Height,
+		DevicePixelRatio: requestConfig.ClientData.DevicePixelRatio,
+	})
+}
+
+// recordCurrentAssets records the assets a device has been sent in the device registry.
+func recordCurrentAssets(deviceID string, viewData common.ViewData) {
+	if deviceID == "" {
+		return
+	}
+
+	assetIDs := make([]string, 0, len(viewData.Images))
+	for _, image := range viewData.Images {
+		assetIDs = append(assetIDs, image.ImmichImage.ID)
+	}
+
+	devices.SetCurrentAssets(deviceID, assetIDs)
+}
@@ -0,0 +1,98 @@
+package routes
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/disintegration/imaging"
+	"github.com/labstack/echo/v4"
+
+	"github.com/damongolding/immich-kiosk/internal/config"
+	"github.com/damongolding/immich-kiosk/internal/utils"
+)
+
+// einkPalette returns the colour palette for the given eink_palette value.
+func einkPalette(name string) (color.Palette, error) {
+	switch strings.ToLower(name) {
+	case config.EinkPaletteMono:
+		return utils.PaletteMono, nil
+	case config.EinkPaletteGrey4:
+		return utils.PaletteGrey4, nil
+	case config.EinkPaletteACeP7:
+		return utils.PaletteACeP7, nil
+	default:
+		return nil, fmt.Errorf("unknown e-ink palette: %s", name)
+	}
+}
+
+// einkDiffusionKernel returns the error diffusion kernel for the given eink_dither value.
+// Unknown values fall back to Floyd–Steinberg.
+func einkDiffusionKernel(name string) utils.DiffusionKernel {
+	switch strings.ToLower(name) {
+	case config.EinkDitherAtkinson:
+		return utils.Atkinson
+	case config.EinkDitherNone:
+		return utils.NoDiffusion
+	default:
+		return utils.FloydSteinberg
+	}
+}
+
+// renderEinkImage resizes the image to the panel resolution, dithers it to the configured
+// e-ink palette and writes it to the response as either a PNG or a packed raw bitmap.
+// Raw bitmaps are described by the X-Eink-Width, X-Eink-Height and X-Eink-Bits-Per-Pixel headers.
+func renderEinkImage(c echo.Context, img image.Image, requestConfig config.Config) error {
+
+	palette, err := einkPalette(requestConfig.EinkPalette)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+
+	width, height := frameDimensions(requestConfig.ClientData)
+	if err := utils.CheckPixelBudget(width, height); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+
+	// the panel needs as much memory as a decoded image, so it waits for a decode slot
+	release := utils.AcquireDecodeSlot()
+	defer release()
+
+	panel := imaging.New(width, height, color.White)
+	panel = imaging.PasteCenter(panel, fitFrameImage(img, requestConfig.ImageFit, width, height))
+
+	dithered := utils.DitherImage(panel, palette, einkDiffusionKernel(requestConfig.EinkDither))
+
+	if strings.EqualFold(requestConfig.EinkFormat, config.EinkFormatRaw) {
+		packed, err := utils.PackPalettedImage(dithered)
+		if err != nil {
+			return err
+		}
+
+		c.Response().Header().Set("X-Eink-Width", strconv.Itoa(width))
+		c.Response().Header().Set("X-Eink-Height", strconv.Itoa(height))
+		c.Response().Header().Set("X-Eink-Bits-Per-Pixel", strconv.Itoa(utils.BitsPerPixel(len(palette))))
+
+		return c.Blob(http.StatusOK, einkMimeType(config.EinkFormatRaw), packed)
+	}
+
+	buf := new(bytes.Buffer)
+	if err := imaging.Encode(buf, dithered, imaging.PNG); err != nil {
+		return err
+	}
+
+	return c.Blob(http.StatusOK, einkMimeType(config.EinkFormatPNG), buf.Bytes())
+}
+
+// einkMimeType returns the MIME type renderEinkImage sends for an e-ink format
+func einkMimeType(format string) string {
+	if strings.EqualFold(format, config.EinkFormatRaw) {
+		return echo.MIMEOctetStream
+	}
+
+	return "image/png"
+}
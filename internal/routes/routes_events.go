@@ -0,0 +1,160 @@
+package routes
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/charmbracelet/log"
+	"github.com/labstack/echo/v4"
+
+	"github.com/damongolding/immich-kiosk/internal/common"
+	"github.com/damongolding/immich-kiosk/internal/config"
+	"github.com/damongolding/immich-kiosk/internal/devices"
+)
+
+const (
+	// pushCheckInterval how often a push connection checks for config reloads and sleep changes
+	pushCheckInterval = time.Second
+	// pushHeartbeatInterval how often a push connection is kept alive and the device marked as seen
+	pushHeartbeatInterval = 15 * time.Second
+	// pushRetry how long the browser waits before reconnecting a dropped push connection
+	pushRetry = 5 * time.Second
+)
+
+// Events opens a Server-Sent Events push channel to a device.
+// It pushes a reload when Kiosk is updated or its config is reloaded, commands sent to the device
+// and changes to its sleep state, so the device does not have to wait for its next check in.
+func Events(baseConfig *config.Config) echo.HandlerFunc {
+	return func(c echo.Context) error {
+
+		requestData, err := InitializeRequestData(c, baseConfig)
+		if err != nil {
+			return err
+		}
+
+		if requestData == nil {
+			log.Info("Refreshing clients")
+			return nil
+		}
+
+		requestConfig := requestData.RequestConfig
+		requestID := requestData.RequestID
+		deviceID := requestData.DeviceID
+
+		if deviceID == "" {
+			return echo.NewHTTPError(http.StatusBadRequest, "Missing device ID")
+		}
+
+		log.Debug(
+			requestID,
+			"method", c.Request().Method,
+			"deviceID", deviceID,
+			"path", c.Request().URL.String(),
+		)
+
+		subscription := devices.Subscribe(deviceID)
+		defer subscription.Close()
+
+		w := c.Response()
+		w.Header().Set(echo.HeaderContentType, "text/event-stream")
+		w.Header().Set(echo.HeaderCacheControl, "no-cache")
+		// stop reverse proxies buffering the stream
+		w.Header().Set("X-Accel-Buffering", "no")
+		w.WriteHeader(http.StatusOK)
+
+		if _, err := fmt.Fprintf(w, "retry: %d\n\n", pushRetry.Milliseconds()); err != nil {
+			return nil
+		}
+		w.Flush()
+
+		checkTicker := time.NewTicker(pushCheckInterval)
+		defer checkTicker.Stop()
+
+		heartbeatTicker := time.NewTicker(pushHeartbeatInterval)
+		defer heartbeatTicker.Stop()
+
+		sleeping := false
+
+		// check pushes a reload or sleep change if needed, returning true once the device has been told to reload
+		check := func() (bool, error) {
+			if c.QueryParam("version") != KioskVersion || c.QueryParam("reload") != baseConfig.ReloadTimeStamp {
+				return true, writePushEvent(w, "reload", nil)
+			}
+
+			if isSleeping := isSleepMode(requestConfig) || devices.IsSleeping(deviceID); isSleeping != sleeping {
+				sleeping = isSleeping
+				return false, writePushEvent(w, "sleep", map[string]bool{"sleeping": sleeping})
+			}
+
+			return false, nil
+		}
+
+		if reload, err := check(); err != nil || reload {
+			return nil
+		}
+
+		for {
+			var reload bool
+
+			select {
+			case <-c.Request().Context().Done():
+				return nil
+
+			case <-common.Context.Done():
+				return nil
+
+			case <-subscription.Wake():
+				pending := subscription.TakeCommands()
+				if len(pending) == 0 {
+					continue
+				}
+
+				if slices.ContainsFunc(pending, func(command devices.Command) bool {
+					return command.Action == devices.ActionReload
+				}) {
+					reload = true
+					err = writePushEvent(w, "reload", nil)
+					break
+				}
+
+				err = writePushEvent(w, "commands", map[string]any{"commands": pending})
+
+			case <-checkTicker.C:
+				reload, err = check()
+
+			case <-heartbeatTicker.C:
+				registerDevice(c, deviceID, requestData.ClientName, requestConfig)
+				_, err = fmt.Fprint(w, ": ping\n\n")
+				w.Flush()
+			}
+
+			if err != nil {
+				log.Debug(requestID, "deviceID", deviceID, "push connection closed", err)
+				return nil
+			}
+
+			if reload {
+				return nil
+			}
+		}
+	}
+}
+
+// writePushEvent writes a Server-Sent Event with its data encoded as JSON and flushes it to the device.
+func writePushEvent(w *echo.Response, event string, data any) error {
+	payload, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+
+	if _, err := fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event, payload); err != nil {
+		return err
+	}
+
+	w.Flush()
+
+	return nil
+}
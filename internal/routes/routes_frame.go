@@ -0,0 +1,533 @@
+package routes
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"math"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/charmbracelet/log"
+	"github.com/disintegration/imaging"
+	"github.com/fogleman/gg"
+	"github.com/goodsign/monday"
+	"github.com/labstack/echo/v4"
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/gofont/gobold"
+	"golang.org/x/image/font/gofont/goregular"
+	"golang.org/x/image/font/opentype"
+
+	"github.com/damongolding/immich-kiosk/internal/config"
+	"github.com/damongolding/immich-kiosk/internal/immich"
+	"github.com/damongolding/immich-kiosk/internal/kiosk"
+	"github.com/damongolding/immich-kiosk/internal/utils"
+	"github.com/damongolding/immich-kiosk/internal/weather"
+)
+
+const (
+	defaultFrameWidth  = 1920
+	defaultFrameHeight = 1080
+	maxFrameDimension  = 7680
+	// frameMatteBorder is the matte border size as a fraction of the shortest frame side
+	frameMatteBorder = 0.06
+)
+
+var (
+	frameFontsOnce sync.Once
+	frameFontsErr  error
+
+	frameFontRegular *opentype.Font
+	frameFontBold    *opentype.Font
+)
+
+// frameTextLine is a single line of overlay text drawn onto a frame
+type frameTextLine struct {
+	Text  string
+	Scale float64
+	Bold  bool
+}
+
+// NewFrameImage returns an echo.HandlerFunc that renders a complete kiosk frame as a single JPEG.
+// The image, blurred background, clock, date, weather and image metadata are composited server-side
+// at the requested resolution (client_width and client_height), honouring the same config and
+// query parameters as the HTML view. This allows devices that can only fetch an image URL to be used.
+func NewFrameImage(baseConfig *config.Config) echo.HandlerFunc {
+	return func(c echo.Context) error {
+
+		requestData, err := InitializeRequestData(c, baseConfig)
+		if err != nil {
+			return err
+		}
+
+		if requestData == nil {
+			log.Info("Refreshing clients")
+			return nil
+		}
+
+		requestConfig := requestData.RequestConfig
+		requestID := requestData.RequestID
+
+		log.Debug(
+			requestID,
+			"method", c.Request().Method,
+			"path", c.Request().URL.String(),
+			"requestConfig", requestConfig.String(),
+		)
+
+		width, height := frameDimensions(requestConfig.ClientData)
+		if err := utils.CheckPixelBudget(width, height); err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+		}
+
+		sleeping := isSleepMode(requestConfig)
+
+		var img image.Image
+		var immichImage immich.ImmichAsset
+
+		if !sleeping {
+			immichImage = immich.NewImage(requestConfig)
+
+			img, err = processImage(&immichImage, requestConfig, requestID, "", false)
+			if err != nil {
+				return err
+			}
+
+			img = applyImageFilters(img, requestConfig, requestID, "", false)
+			img = debugOverlay(img, &immichImage, requestConfig, requestID, "")
+		}
+
+		// rendering the frame needs as much memory as decoding an image, so it waits for a decode slot.
+		// The image is processed first, as decoding takes a slot of its own.
+		imgBytes, err := func() ([]byte, error) {
+			defer utils.AcquireDecodeSlot()()
+
+			// frame.jpg is always a JPEG, only the quality is configurable
+			if sleeping {
+				return utils.ImageToBytes(renderSleepFrame(requestConfig, width, height), utils.ImageFormatJPEG, requestConfig.ImageQuality)
+			}
+
+			frame, err := renderFrame(img, &immichImage, requestConfig, c.QueryParam("weather"), width, height)
+			if err != nil {
+				return nil, err
+			}
+
+			return utils.ImageToBytes(frame, utils.ImageFormatJPEG, requestConfig.ImageQuality)
+		}()
+		if err != nil {
+			return err
+		}
+
+		c.Response().Header().Set(echo.HeaderCacheControl, "no-store")
+		return c.Blob(http.StatusOK, utils.ImageFormatMimeType(utils.ImageFormatJPEG), imgBytes)
+	}
+}
+
+// frameDimensions returns the size of the frame to render based on the client data.
+// Missing dimensions fall back to 1920x1080 and both dimensions are clamped to maxFrameDimension.
+// Callers that allocate a frame must also check its size with utils.CheckPixelBudget.
+func frameDimensions(clientData config.ClientData) (int, int) {
+	width, height := clientData.Width, clientData.Height
+
+	if width <= 0 || height <= 0 {
+		return defaultFrameWidth, defaultFrameHeight
+	}
+
+	return min(width, maxFrameDimension), min(height, maxFrameDimension)
+}
+
+// loadFrameFonts parses the embedded Go fonts used for frame overlays.
+// Parsing only happens once and the result is shared between requests.
+func loadFrameFonts() error {
+	frameFontsOnce.Do(func() {
+		frameFontRegular, frameFontsErr = opentype.Parse(goregular.TTF)
+		if frameFontsErr != nil {
+			return
+		}
+		frameFontBold, frameFontsErr = opentype.Parse(gobold.TTF)
+	})
+
+	return frameFontsErr
+}
+
+// frameFontFace returns a font face of the given size.
+func frameFontFace(bold bool, size float64) (font.Face, error) {
+	f := frameFontRegular
+	if bold {
+		f = frameFontBold
+	}
+
+	return opentype.NewFace(f, &opentype.FaceOptions{
+		Size:    size,
+		DPI:     72,
+		Hinting: font.HintingFull,
+	})
+}
+
+// renderSleepFrame renders a black frame, with a faint clock if the clock or date is enabled.
+func renderSleepFrame(requestConfig config.Config, width, height int) image.Image {
+	dc := gg.NewContext(width, height)
+	dc.SetColor(color.Black)
+	dc.Clear()
+
+	lines := frameClockLines(requestConfig, time.Now())
+	if len(lines) == 0 {
+		return dc.Image()
+	}
+
+	if err := loadFrameFonts(); err != nil {
+		log.Error("loading frame fonts", "err", err)
+		return dc.Image()
+	}
+
+	drawFrameTextBlock(dc, lines, frameBaseFontSize(requestConfig, height), float64(width)/2, float64(height)/2, 0.5, 0.5, "", color.RGBA{R: 255, G: 255, B: 255, A: 60})
+
+	return dc.Image()
+}
+
+// renderFrame composites the image, background and overlays into a single image of the given size.
+func renderFrame(img image.Image, immichImage *immich.ImmichAsset, requestConfig config.Config, weatherLocation string, width, height int) (image.Image, error) {
+
+	dc := gg.NewContext(width, height)
+	dc.SetColor(color.Black)
+	dc.Clear()
+
+	if requestConfig.BackgroundBlur && !strings.EqualFold(requestConfig.ImageFit, "cover") {
+		if err := drawFrameBackground(dc, img, requestConfig.BackgroundStyle, width, height); err != nil {
+			return nil, err
+		}
+	}
+
+	imageWidth, imageHeight := width, height
+	if requestConfig.BackgroundBlur && requestConfig.BackgroundStyle == config.BackgroundStyleMatte && !strings.EqualFold(requestConfig.ImageFit, "cover") {
+		// leave a passe-partout border around the image
+		border := int(float64(min(width, height)) * frameMatteBorder)
+		imageWidth, imageHeight = width-border*2, height-border*2
+	}
+
+	dc.DrawImageAnchored(fitFrameImage(img, requestConfig.ImageFit, imageWidth, imageHeight), width/2, height/2, 0.5, 0.5)
+
+	if requestConfig.DisableUi {
+		return dc.Image(), nil
+	}
+
+	if err := loadFrameFonts(); err != nil {
+		return nil, fmt.Errorf("loading frame fonts: %w", err)
+	}
+
+	baseSize := frameBaseFontSize(requestConfig, height)
+	padding := baseSize * 1.5
+
+	if weatherLocation == "" && requestConfig.HasWeatherDefault {
+		weatherLocation = weather.DefaultLocation()
+	}
+
+	topLeft := frameClockLines(requestConfig, time.Now())
+	topLeft = append(topLeft, frameWeatherLines(weatherLocation)...)
+	drawFrameTextBlock(dc, topLeft, baseSize, padding, padding, 0, 0, requestConfig.Theme, color.White)
+
+	bottomRight := frameMetadataLines(requestConfig, immichImage)
+	drawFrameTextBlock(dc, bottomRight, baseSize, float64(width)-padding, float64(height)-padding, 1, 1, requestConfig.Theme, color.White)
+
+	return dc.Image(), nil
+}
+
+// drawFrameBackground fills the frame with the configured background style.
+func drawFrameBackground(dc *gg.Context, img image.Image, style string, width, height int) error {
+	switch style {
+	case config.BackgroundStyleDominantColor:
+		c := utils.DominantColor(img)
+		dc.SetRGB255(c.R, c.G, c.B)
+		dc.Clear()
+	case config.BackgroundStyleMatte:
+		c := utils.MatteColor(img)
+		dc.SetRGB255(c.R, c.G, c.B)
+		dc.Clear()
+	case config.BackgroundStyleGradient:
+		top, bottom, left, right := utils.EdgeColors(img)
+		from, to := top, bottom
+		gradient := gg.NewLinearGradient(0, 0, 0, float64(height))
+		if img.Bounds().Dy() > img.Bounds().Dx() {
+			from, to = left, right
+			gradient = gg.NewLinearGradient(0, 0, float64(width), 0)
+		}
+		gradient.AddColorStop(0, color.RGBA{R: uint8(from.R), G: uint8(from.G), B: uint8(from.B), A: 255})
+		gradient.AddColorStop(1, color.RGBA{R: uint8(to.R), G: uint8(to.G), B: uint8(to.B), A: 255})
+		dc.SetFillStyle(gradient)
+		dc.DrawRectangle(0, 0, float64(width), float64(height))
+		dc.Fill()
+	default:
+		background, err := utils.BlurImage(imaging.Fill(img, width, height, imaging.Center, imaging.Lanczos), true, width, height)
+		if err != nil {
+			return fmt.Errorf("blurring frame background: %w", err)
+		}
+		dc.DrawImage(background, 0, 0)
+	}
+
+	return nil
+}
+
+// fitFrameImage scales the image to the frame following the image_fit option.
+//   - cover: fill the frame, cropping from the centre
+//   - none: display as is, only scaling down images larger than the frame
+//   - contain (default): scale up or down until the image touches the frame edges
+func fitFrameImage(img image.Image, imageFit string, width, height int) image.Image {
+	bounds := img.Bounds()
+
+	switch strings.ToLower(imageFit) {
+	case "cover":
+		return imaging.Fill(img, width, height, imaging.Center, imaging.Lanczos)
+	case "none":
+		if bounds.Dx() <= width && bounds.Dy() <= height {
+			return img
+		}
+		return imaging.Fit(img, width, height, imaging.Lanczos)
+	default:
+		ratio := math.Min(float64(width)/float64(bounds.Dx()), float64(height)/float64(bounds.Dy()))
+		return imaging.Resize(img, int(math.Round(float64(bounds.Dx())*ratio)), int(math.Round(float64(bounds.Dy())*ratio)), imaging.Lanczos)
+	}
+}
+
+// frameBaseFontSize returns the base font size in pixels, scaled by frame height and the font_size option.
+func frameBaseFontSize(requestConfig config.Config, height int) float64 {
+	fontSize := requestConfig.FontSize
+	if fontSize <= 0 {
+		fontSize = 100
+	}
+	return float64(height) / 45 * float64(fontSize) / 100
+}
+
+// drawFrameTextBlock draws lines of text anchored at x,y. ax and ay work like gg anchors
+// (0 = left/top, 1 = right/bottom). The theme decides what is drawn behind the text to keep it readable.
+func drawFrameTextBlock(dc *gg.Context, lines []frameTextLine, baseSize, x, y, ax, ay float64, theme string, textColor color.Color) {
+	if len(lines) == 0 {
+		return
+	}
+
+	type measuredLine struct {
+		frameTextLine
+		face   font.Face
+		width  float64
+		height float64
+	}
+
+	measured := make([]measuredLine, 0, len(lines))
+	var blockWidth, blockHeight float64
+
+	for _, line := range lines {
+		face, err := frameFontFace(line.Bold, baseSize*line.Scale)
+		if err != nil {
+			log.Error("creating frame font face", "err", err)
+			continue
+		}
+		dc.SetFontFace(face)
+		w, _ := dc.MeasureString(line.Text)
+		h := baseSize * line.Scale * 1.3
+
+		measured = append(measured, measuredLine{frameTextLine: line, face: face, width: w, height: h})
+		blockWidth = max(blockWidth, w)
+		blockHeight += h
+	}
+
+	left := x - blockWidth*ax
+	top := y - blockHeight*ay
+	padding := baseSize * 0.75
+
+	switch theme {
+	case "solid":
+		dc.SetRGBA(0, 0, 0, 0.6)
+		dc.DrawRoundedRectangle(left-padding, top-padding, blockWidth+padding*2, blockHeight+padding*2, padding)
+		dc.Fill()
+	case "fade":
+		gradient := gg.NewLinearGradient(0, top-padding*2, 0, top+blockHeight+padding*2)
+		if ay == 0 {
+			gradient.AddColorStop(0, color.RGBA{A: 150})
+			gradient.AddColorStop(1, color.RGBA{A: 0})
+		} else {
+			gradient.AddColorStop(0, color.RGBA{A: 0})
+			gradient.AddColorStop(1, color.RGBA{A: 150})
+		}
+		dc.SetFillStyle(gradient)
+		dc.DrawRectangle(left-padding*4, top-padding*2, blockWidth+padding*8, blockHeight+padding*4)
+		dc.Fill()
+	}
+
+	lineTop := top
+	for _, line := range measured {
+		dc.SetFontFace(line.face)
+		dc.SetColor(textColor)
+		dc.DrawStringAnchored(line.Text, left+(blockWidth-line.width)*ax, lineTop+line.height/2, 0, 0.35)
+		lineTop += line.height
+	}
+}
+
+// frameClockLines returns the clock and date lines for the frame based on show_time and show_date.
+func frameClockLines(requestConfig config.Config, now time.Time) []frameTextLine {
+	var lines []frameTextLine
+
+	if requestConfig.ShowTime {
+		timeLayout := "15:04"
+		if requestConfig.TimeFormat == "12" {
+			timeLayout = "3:04 PM"
+		}
+		lines = append(lines, frameTextLine{Text: now.Format(timeLayout), Scale: 3, Bold: true})
+	}
+
+	if requestConfig.ShowDate {
+		dateLayout := config.DefaultDateLayout
+		if requestConfig.DateFormat != "" {
+			dateLayout = utils.DateToLayout(requestConfig.DateFormat)
+		}
+		lines = append(lines, frameTextLine{Text: monday.Format(now, dateLayout, requestConfig.SystemLang), Scale: 1.2})
+	}
+
+	return lines
+}
+
+// frameWeatherLines returns the current weather for the given location, if any data is available.
+func frameWeatherLines(locationName string) []frameTextLine {
+	if locationName == "" {
+		return nil
+	}
+
+	location := weather.CurrentWeather(locationName)
+	if location.Name == "" || len(location.Data) == 0 {
+		return nil
+	}
+
+	unit := "K"
+	switch strings.ToLower(location.Unit) {
+	case "metric":
+		unit = "°C"
+	case "imperial":
+		unit = "°F"
+	}
+
+	return []frameTextLine{
+		{Text: fmt.Sprintf("%s %.0f%s", location.Name, location.Main.Temp, unit), Scale: 1.2, Bold: true},
+		{Text: location.Data[0].Description, Scale: 1},
+	}
+}
+
+// frameMetadataLines returns the image metadata lines for the frame based on the show_image_* options.
+func frameMetadataLines(requestConfig config.Config, immichImage *immich.ImmichAsset) []frameTextLine {
+	var lines []frameTextLine
+
+	if (requestConfig.ShowAlbumName && immichImage.KioskSource == kiosk.SourceAlbums) ||
+		(requestConfig.ShowPersonName && immichImage.KioskSource == kiosk.SourcePerson) {
+		if immichImage.KioskSourceName != "" {
+			lines = append(lines, frameTextLine{Text: immichImage.KioskSourceName, Scale: 1.2, Bold: true})
+		}
+	}
+
+	if dateTime := frameImageDateTime(requestConfig, immichImage); dateTime != "" {
+		lines = append(lines, frameTextLine{Text: dateTime, Scale: 1})
+	}
+
+	if requestConfig.ShowImageDescription && immichImage.ExifInfo.Description != "" {
+		lines = append(lines, frameTextLine{Text: immichImage.ExifInfo.Description, Scale: 1})
+	}
+
+	if requestConfig.ShowImageExif {
+		if exif := frameImageExif(immichImage.ExifInfo); exif != "" {
+			lines = append(lines, frameTextLine{Text: exif, Scale: 0.9})
+		}
+	}
+
+	if requestConfig.ShowImageLocation {
+		if location := frameImageLocation(immichImage.ExifInfo, requestConfig.HideCountries); location != "" {
+			lines = append(lines, frameTextLine{Text: location, Scale: 0.9})
+		}
+	}
+
+	if requestConfig.ShowImageID {
+		lines = append(lines, frameTextLine{Text: immichImage.ID, Scale: 0.7})
+	}
+
+	return lines
+}
+
+// frameImageDateTime formats the image date and/or time using the image_date_format and image_time_format options.
+func frameImageDateTime(requestConfig config.Config, immichImage *immich.ImmichAsset) string {
+	if !requestConfig.ShowImageDate && !requestConfig.ShowImageTime {
+		return ""
+	}
+
+	imageDate := immichImage.ExifInfo.DateTimeOriginal
+	if imageDate.IsZero() {
+		imageDate = immichImage.LocalDateTime
+	}
+	if imageDate.IsZero() {
+		return ""
+	}
+
+	var parts []string
+
+	if requestConfig.ShowImageDate {
+		dateLayout := config.DefaultDateLayout
+		if requestConfig.ImageDateFormat != "" {
+			dateLayout = utils.DateToLayout(requestConfig.ImageDateFormat)
+		}
+		parts = append(parts, monday.Format(imageDate, dateLayout, requestConfig.SystemLang))
+	}
+
+	if requestConfig.ShowImageTime {
+		timeLayout := "15:04"
+		if requestConfig.ImageTimeFormat == "12" {
+			timeLayout = "3:04 PM"
+		}
+		parts = append(parts, imageDate.Format(timeLayout))
+	}
+
+	return strings.Join(parts, " ")
+}
+
+// frameImageExif formats the f number, shutter speed, focal length and ISO of an image.
+func frameImageExif(exif immich.ExifInfo) string {
+	var parts []string
+
+	if exif.FNumber != 0 {
+		parts = append(parts, fmt.Sprintf("ƒ/%.1f", exif.FNumber))
+	}
+	if exif.ExposureTime != "" {
+		parts = append(parts, exif.ExposureTime+"s")
+	}
+	if exif.FocalLength != 0 {
+		parts = append(parts, fmt.Sprintf("%.0fmm", exif.FocalLength))
+	}
+	if exif.Iso != 0 {
+		parts = append(parts, fmt.Sprintf("ISO %d", exif.Iso))
+	}
+
+	return strings.Join(parts, " · ")
+}
+
+// frameImageLocation formats the city, state and country of an image,
+// omitting the country if it is in hideCountries (which are expected to be lowercase).
+func frameImageLocation(exif immich.ExifInfo, hideCountries []string) string {
+	var parts []string
+
+	if exif.City != "" {
+		parts = append(parts, exif.City)
+	}
+	if exif.State != "" && exif.State != exif.City {
+		parts = append(parts, exif.State)
+	}
+
+	if exif.Country != "" {
+		hidden := false
+		for _, country := range hideCountries {
+			if strings.EqualFold(country, exif.Country) {
+				hidden = true
+				break
+			}
+		}
+		if !hidden {
+			parts = append(parts, exif.Country)
+		}
+	}
+
+	return strings.Join(parts, ", ")
+}
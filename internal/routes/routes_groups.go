@@ -0,0 +1,72 @@
+package routes
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/labstack/echo/v4"
+
+	"github.com/damongolding/immich-kiosk/internal/common"
+	"github.com/damongolding/immich-kiosk/internal/config"
+	"github.com/damongolding/immich-kiosk/internal/groups"
+)
+
+// slideDelayHeader tells the frontend how long (in milliseconds) to wait before requesting the next slide
+const slideDelayHeader = "kiosk-slide-delay"
+
+// isGroupMode reports whether the device belongs to a slideshow group
+func isGroupMode(requestConfig config.Config) bool {
+	return requestConfig.Group != "" && requestConfig.Refresh > 0
+}
+
+// groupViewData returns the slide the device's group shows in the upcoming slot, or its tile of it for video walls.
+// The first device to ask builds the slide, the others in the group wait for and share it.
+// It sets the headers that tell the device when to show the slide and when to ask for the next one.
+func groupViewData(c echo.Context, requestConfig config.Config, deviceID string) (common.ViewData, error) {
+	interval := time.Duration(requestConfig.Refresh) * time.Second
+
+	now := time.Now()
+	slot := groups.SlotFor(now, interval)
+
+	position := groups.Join(requestConfig.Group, deviceID, now, interval)
+	if requestConfig.GroupMode != config.GroupModeDifferent || position < 0 {
+		position = 0
+	}
+
+	var viewData common.ViewData
+	var err error
+
+	if isVideoWall(requestConfig) {
+		viewData, err = videoWallViewData(c, requestConfig, deviceID, slot)
+	} else {
+		viewData, err = groups.Slide(requestConfig.Group, slot, position, func() (common.ViewData, error) {
+			return generateViewData(requestConfig, c, deviceID, false)
+		})
+
+		// the slide may have been built for another device
+		viewData.DeviceID = deviceID
+		viewData.Config = requestConfig
+	}
+	if err != nil {
+		return viewData, err
+	}
+
+	now = time.Now()
+
+	c.Response().Header().Set("HX-Reswap", groupSwap(requestConfig, slot.ShowIn(now)))
+	c.Response().Header().Set(slideDelayHeader, strconv.FormatInt(slot.NextRequestIn(now).Milliseconds(), 10))
+
+	return viewData, nil
+}
+
+// groupSwap returns the htmx swap that shows a group slide once its slot starts.
+// The fade transition already delays the swap while the old slide fades out, so the longer of the two is used.
+func groupSwap(requestConfig config.Config, showIn time.Duration) string {
+	if requestConfig.Transition == "fade" {
+		fade := time.Duration(float64(requestConfig.FadeTransitionDuration) * float64(time.Second))
+		return fmt.Sprintf("innerHTML swap:%dms", max(showIn, fade).Milliseconds())
+	}
+
+	return fmt.Sprintf("beforeend swap:%dms", showIn.Milliseconds())
+}
@@ -9,6 +9,7 @@ import (
 
 	"github.com/damongolding/immich-kiosk/internal/common"
 	"github.com/damongolding/immich-kiosk/internal/config"
+	"github.com/damongolding/immich-kiosk/internal/devices"
 	"github.com/damongolding/immich-kiosk/internal/templates/views"
 	"github.com/damongolding/immich-kiosk/internal/utils"
 	"github.com/damongolding/immich-kiosk/internal/weather"
@@ -23,6 +24,8 @@ func Home(baseConfig *config.Config) echo.HandlerFunc {
 			MaxAge: -1,
 		})
 
+		deviceID, isNewDevice := ensureDeviceID(c, baseConfig)
+
 		requestData, err := InitializeRequestData(c, baseConfig)
 		if err != nil {
 			return err
@@ -33,6 +36,11 @@ func Home(baseConfig *config.Config) echo.HandlerFunc {
 			return nil
 		}
 
+		// a sleeping device that reloads goes straight back to sleep
+		if devices.IsSleeping(deviceID) {
+			devices.SendCommand(deviceID, devices.Command{Action: devices.ActionSleep})
+		}
+
 		requestConfig := requestData.RequestConfig
 		requestID := requestData.RequestID
 
@@ -57,7 +65,8 @@ func Home(baseConfig *config.Config) echo.HandlerFunc {
 
 		viewData := common.ViewData{
 			KioskVersion: KioskVersion,
-			DeviceID:     utils.GenerateUUID(),
+			DeviceID:     deviceID,
+			NewDevice:    isNewDevice,
 			Queries:      queryParams,
 			CustomCss:    customCss,
 			Config:       requestConfig,
@@ -2,11 +2,14 @@ package routes
 
 import (
 	"net/http"
+	"strconv"
+	"strings"
 
 	"github.com/charmbracelet/log"
 	"github.com/labstack/echo/v4"
 
 	"github.com/damongolding/immich-kiosk/internal/config"
+	"github.com/damongolding/immich-kiosk/internal/devices"
 	"github.com/damongolding/immich-kiosk/internal/immich"
 	imageComponent "github.com/damongolding/immich-kiosk/internal/templates/components/image"
 	"github.com/damongolding/immich-kiosk/internal/utils"
@@ -40,16 +43,41 @@ func NewImage(baseConfig *config.Config) echo.HandlerFunc {
 			"requestConfig", requestConfig.String(),
 		)
 
-		if isSleepMode(requestConfig) {
+		if isSleepMode(requestConfig) || devices.IsSleeping(deviceID) {
 			return c.NoContent(http.StatusNoContent)
 		}
 
+		// show the asset the device was remotely asked to show (if any)
+		if assetID, ok := devices.TakeQueuedAsset(deviceID); ok {
+			viewData, err := generateAssetViewData(assetID, requestConfig, requestID, deviceID)
+			if err != nil {
+				return RenderError(c, err, "retrieving image")
+			}
+
+			go webhooks.Trigger(requestData, KioskVersion, webhooks.NewAsset, viewData)
+			recordCurrentAssets(deviceID, viewData)
+			return Render(c, http.StatusOK, imageComponent.Image(viewData))
+		}
+
+		// devices in a group show the slide the server has scheduled for the group
+		if isGroupMode(requestConfig) {
+			viewData, err := groupViewData(c, requestConfig, deviceID)
+			if err != nil {
+				return RenderError(c, err, "retrieving image")
+			}
+
+			go webhooks.Trigger(requestData, KioskVersion, webhooks.NewAsset, viewData)
+			recordCurrentAssets(deviceID, viewData)
+			return Render(c, http.StatusOK, imageComponent.Image(viewData))
+		}
+
 		// get and use prefetch data (if found)
 		if requestConfig.Kiosk.PreFetch {
 			if cachedViewData := fromCache(c.Request().URL.String(), deviceID); cachedViewData != nil {
 				requestEchoCtx := c
 				go imagePreFetch(requestData, requestEchoCtx)
 				go webhooks.Trigger(requestData, KioskVersion, webhooks.NewAsset, cachedViewData[0])
+				recordCurrentAssets(deviceID, cachedViewData[0])
 				return renderCachedViewData(c, cachedViewData, &requestConfig, requestID, deviceID)
 			}
 			log.Debug(requestID, "deviceID", deviceID, "cache miss for new image")
@@ -66,6 +94,7 @@ func NewImage(baseConfig *config.Config) echo.HandlerFunc {
 		}
 
 		go webhooks.Trigger(requestData, KioskVersion, webhooks.NewAsset, viewData)
+		recordCurrentAssets(deviceID, viewData)
 		return Render(c, http.StatusOK, imageComponent.Image(viewData))
 	}
 }
@@ -102,11 +131,85 @@ func NewRawImage(baseConfig *config.Config) echo.HandlerFunc {
 			return err
 		}
 
-		imgBytes, err := utils.ImageToBytes(img)
+		img = applyImageFilters(img, requestConfig, requestID, "", false)
+		img = debugOverlay(img, &immichImage, requestConfig, requestID, "")
+
+		if requestConfig.EinkPalette != "" {
+			return renderEinkImage(c, img, requestConfig)
+		}
+
+		format := negotiateImageFormat(c.Request().Header.Get(echo.HeaderAccept), requestConfig.ImageFormat)
+
+		imgBytes, err := utils.ImageToBytes(img, format, requestConfig.ImageQuality)
 		if err != nil {
 			return err
 		}
 
-		return c.Blob(http.StatusOK, "image/jpeg", imgBytes)
+		c.Response().Header().Set(echo.HeaderVary, echo.HeaderAccept)
+		return c.Blob(http.StatusOK, utils.ImageFormatMimeType(format), imgBytes)
+	}
+}
+
+// negotiateImageFormat picks jpeg or png for a raw image from the request's Accept header.
+// The format with the highest q-value wins, ties and headers that only list wildcards
+// (or no header at all) use the configured fallback format.
+func negotiateImageFormat(accept, fallback string) string {
+	if fallback != utils.ImageFormatPNG {
+		fallback = utils.ImageFormatJPEG
+	}
+
+	other := utils.ImageFormatPNG
+	if fallback == utils.ImageFormatPNG {
+		other = utils.ImageFormatJPEG
+	}
+
+	if strings.TrimSpace(accept) == "" {
+		return fallback
+	}
+
+	explicit := make(map[string]float64)
+	wildcard := -1.0
+
+	for _, part := range strings.Split(accept, ",") {
+		params := strings.Split(part, ";")
+		mediaType := strings.ToLower(strings.TrimSpace(params[0]))
+
+		q := 1.0
+		for _, param := range params[1:] {
+			key, value, found := strings.Cut(strings.TrimSpace(param), "=")
+			if !found || !strings.EqualFold(key, "q") {
+				continue
+			}
+
+			parsed, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				continue
+			}
+			q = parsed
+		}
+
+		switch mediaType {
+		case "image/png":
+			explicit[utils.ImageFormatPNG] = q
+		case "image/jpeg", "image/jpg":
+			explicit[utils.ImageFormatJPEG] = q
+		case "image/*", "*/*":
+			wildcard = max(wildcard, q)
+		}
+	}
+
+	score := func(format string) float64 {
+		if q, ok := explicit[format]; ok {
+			return q
+		}
+		return wildcard
 	}
+
+	fallbackScore, otherScore := score(fallback), score(other)
+
+	if otherScore > 0 && otherScore > fallbackScore {
+		return other
+	}
+
+	return fallback
 }
@@ -1,6 +1,8 @@
 package routes
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"image"
 	"net/http"
@@ -16,10 +18,40 @@ import (
 	imageComponent "github.com/damongolding/immich-kiosk/internal/templates/components/image"
 	"github.com/damongolding/immich-kiosk/internal/utils"
 	"github.com/damongolding/immich-kiosk/internal/webhooks"
-	"github.com/fogleman/gg"
+	"github.com/disintegration/imaging"
 	"github.com/labstack/echo/v4"
+	"golang.org/x/sync/errgroup"
 )
 
+// imageQualityCacheExpiration is how long quality scores are kept, so each asset is only analysed once a day
+const imageQualityCacheExpiration = 24 * time.Hour
+
+// maxImageRetrievalAttepmts is how many times a layout fetches another image when it gets one it is already showing
+const maxImageRetrievalAttepmts = 3
+
+// collageLayouts maps each multi-image layout to the orientation wanted for each of its slots.
+// Slots are filled in order, so the first slot is the largest tile in the layout.
+var collageLayouts = map[string][]immich.ImageOrientation{
+	"grid-3": {
+		immich.LandscapeOrientation,
+		immich.PortraitOrientation,
+		immich.PortraitOrientation,
+	},
+	"grid-4": {
+		immich.LandscapeOrientation,
+		immich.LandscapeOrientation,
+		immich.LandscapeOrientation,
+		immich.LandscapeOrientation,
+	},
+	"mosaic": {
+		immich.LandscapeOrientation,
+		immich.PortraitOrientation,
+		immich.PortraitOrientation,
+		immich.LandscapeOrientation,
+		immich.LandscapeOrientation,
+	},
+}
+
 // gatherAssetBuckets collects asset weightings for people, albums and date ranges.
 // For each person, it gets the count of images containing that person.
 // For each album, it gets the total count of images in the album.
@@ -166,6 +198,9 @@ func fetchImagePreview(immichImage *immich.ImmichAsset, requestID, deviceID stri
 	}
 
 	img, err := utils.BytesToImage(imgBytes)
+	if errors.Is(err, utils.ErrImageTooLarge) {
+		img, err = decodePreviewRendition(immichImage, err)
+	}
 	if err != nil {
 		return nil, err
 	}
@@ -181,32 +216,142 @@ func fetchImagePreview(immichImage *immich.ImmichAsset, requestID, deviceID stri
 	return img, nil
 }
 
+// decodePreviewRendition is used when an image is over the decode pixel budget.
+// The image is replaced with Immich's (much smaller) preview rendition.
+// If the preview is also over the budget the image is refused.
+func decodePreviewRendition(immichImage *immich.ImmichAsset, tooLargeErr error) (image.Image, error) {
+	log.Warn("Image too large to decode, using preview instead", "id", immichImage.ID, "err", tooLargeErr)
+
+	imgBytes, err := immichImage.ImageRendition(immich.RenditionPreview)
+	if err != nil {
+		return nil, fmt.Errorf("getting image preview: %w", err)
+	}
+
+	return utils.BytesToImage(imgBytes)
+}
+
 // processImage handles the entire process of selecting and retrieving an image.
+// Faces are blurred for privacy here, before the image is used anywhere else.
 // It returns the image bytes and an error if any step fails.
 func processImage(immichImage *immich.ImmichAsset, requestConfig config.Config, requestID string, deviceID string, isPrefetch bool) (image.Image, error) {
+	startTime := time.Now()
 
-	assets, err := gatherAssetBuckets(immichImage, requestConfig, requestID, deviceID)
+	img, err := selectImage(immichImage, requestConfig, requestID, deviceID, isPrefetch)
 	if err != nil {
 		return nil, err
 	}
 
-	pickedAsset := utils.PickRandomImageType(requestConfig.Kiosk.AssetWeighting, assets)
+	immichImage.KioskSelectionTime = time.Since(startTime)
+
+	return applyPrivacyBlur(img, immichImage, requestConfig, requestID, deviceID, isPrefetch)
+}
 
-	if err := retrieveImage(immichImage, pickedAsset, requestConfig.AlbumOrder, requestConfig.ExcludedAlbums, requestID, deviceID, isPrefetch); err != nil {
+// selectImage picks an asset and retrieves its image.
+// When the quality filter is enabled, low quality images are skipped and selection is retried
+// up to immich.MaxRetries times, after which the last candidate is used.
+func selectImage(immichImage *immich.ImmichAsset, requestConfig config.Config, requestID string, deviceID string, isPrefetch bool) (image.Image, error) {
+
+	assets, err := gatherAssetBuckets(immichImage, requestConfig, requestID, deviceID)
+	if err != nil {
 		return nil, err
 	}
 
-	immichImage.KioskSource = pickedAsset.Type
+	ratioWanted := immichImage.RatioWanted
+
+	for retries := 0; ; retries++ {
+		immichImage.RatioWanted = ratioWanted
+
+		pickedAsset := utils.PickRandomImageType(requestConfig.Kiosk.AssetWeighting, assets)
+
+		if err := retrieveImage(immichImage, pickedAsset, requestConfig.AlbumOrder, requestConfig.ExcludedAlbums, requestID, deviceID, isPrefetch); err != nil {
+			return nil, err
+		}
+
+		immichImage.KioskSource = pickedAsset.Type
+		if requestConfig.Kiosk.AssetWeighting {
+			immichImage.KioskSourceWeight, immichImage.KioskTotalWeight = assetWeighting(pickedAsset, assets)
+		}
+
+		lastAttempt := retries >= immich.MaxRetries
+
+		if !requestConfig.QualityFilter {
+			return fetchImagePreview(immichImage, requestID, deviceID, isPrefetch)
+		}
+
+		// skip assets already known to be low quality without downloading them again
+		if quality, found := cachedImageQuality(immichImage.ID); found && !lastAttempt {
+			if reason := imageQualityReason(quality, requestConfig); reason != "" {
+				log.Debug(requestID, "Skipping low quality image", "id", immichImage.ID, "reason", reason, "cached", true)
+				continue
+			}
+		}
+
+		img, err := fetchImagePreview(immichImage, requestID, deviceID, isPrefetch)
+		if err != nil {
+			return nil, err
+		}
+
+		if lastAttempt {
+			log.Debug(requestID, "No image passed the quality filter, using last candidate", "id", immichImage.ID)
+			return img, nil
+		}
+
+		if reason := imageQualityReason(imageQuality(immichImage.ID, img), requestConfig); reason != "" {
+			log.Debug(requestID, "Skipping low quality image", "id", immichImage.ID, "reason", reason)
+			continue
+		}
+
+		return img, nil
+	}
+}
+
+// cachedImageQuality returns the previously computed quality scores for an asset.
+func cachedImageQuality(assetID string) (utils.ImageQuality, bool) {
+	if cached, found := cache.Get(cache.QualityCacheKey(assetID)); found {
+		if quality, ok := cached.(utils.ImageQuality); ok {
+			return quality, true
+		}
+	}
+
+	return utils.ImageQuality{}, false
+}
+
+// imageQuality returns the quality scores for an asset, analysing the image only if
+// the asset has not been analysed before.
+func imageQuality(assetID string, img image.Image) utils.ImageQuality {
+	if quality, found := cachedImageQuality(assetID); found {
+		return quality
+	}
+
+	quality := utils.AnalyzeImageQuality(img)
+	cache.SetWithExpiration(cache.QualityCacheKey(assetID), quality, imageQualityCacheExpiration)
 
-	return fetchImagePreview(immichImage, requestID, deviceID, isPrefetch)
+	return quality
 }
 
-// imageToBase64 converts image bytes to a base64 string and logs the processing time.
+// imageQualityReason checks quality scores against the configured thresholds.
+// It returns why the image failed, or an empty string if it passed.
+func imageQualityReason(quality utils.ImageQuality, requestConfig config.Config) string {
+	switch {
+	case quality.Sharpness < requestConfig.MinSharpness:
+		return fmt.Sprintf("blurry (sharpness %.1f < %.1f)", quality.Sharpness, requestConfig.MinSharpness)
+	case quality.Brightness < requestConfig.MinBrightness:
+		return fmt.Sprintf("too dark (brightness %.1f%% < %.1f%%)", quality.Brightness, requestConfig.MinBrightness)
+	case quality.Brightness > requestConfig.MaxBrightness:
+		return fmt.Sprintf("too bright (brightness %.1f%% > %.1f%%)", quality.Brightness, requestConfig.MaxBrightness)
+	case quality.ShadowClipping+quality.HighlightClipping > requestConfig.MaxClipping:
+		return fmt.Sprintf("poorly exposed (%.1f%% clipped > %.1f%%)", quality.ShadowClipping+quality.HighlightClipping, requestConfig.MaxClipping)
+	default:
+		return ""
+	}
+}
+
+// imageToBase64 encodes an image in the given format and quality as a base64 string and logs the processing time.
 // It returns the base64 string and an error if conversion fails.
-func imageToBase64(img image.Image, config config.Config, requestID, deviceID string, action string, isPrefetch bool) (string, error) {
+func imageToBase64(img image.Image, format string, quality int, config config.Config, requestID, deviceID string, action string, isPrefetch bool) (string, error) {
 	startTime := time.Now()
 
-	imgBytes, err := utils.ImageToBase64(img)
+	imgBytes, err := utils.ImageToBase64(img, format, quality)
 	if err != nil {
 		return "", fmt.Errorf("converting image to base64: %w", err)
 	}
@@ -218,7 +363,7 @@ func imageToBase64(img image.Image, config config.Config, requestID, deviceID st
 // processBlurredImage applies a blur effect to the image if required by the configuration.
 // It returns the blurred image as a base64 string and an error if any occurs.
 func processBlurredImage(img image.Image, config config.Config, requestID, deviceID string, isPrefetch bool) (string, error) {
-	if !config.BackgroundBlur || strings.EqualFold(config.ImageFit, "cover") || (config.ImageEffect != "" && config.ImageEffect != "none") {
+	if !config.BackgroundBlur || config.BackgroundStyle != "blur" || strings.EqualFold(config.ImageFit, "cover") || (config.ImageEffect != "" && config.ImageEffect != "none") {
 		return "", nil
 	}
 
@@ -230,68 +375,104 @@ func processBlurredImage(img image.Image, config config.Config, requestID, devic
 
 	logImageProcessing(config, requestID, deviceID, isPrefetch, "Blurred", startTime)
 
-	return imageToBase64(imgBlur, config, requestID, deviceID, "Coverted blurred", isPrefetch)
+	// the background is blurred so detail lost to a lower quality is not visible
+	return imageToBase64(imgBlur, utils.ImageFormatJPEG, config.BackgroundBlurQuality, config, requestID, deviceID, "Coverted blurred", isPrefetch)
 }
 
-// logImageProcessing logs the time taken for image processing if debug verbose is enabled.
-func logImageProcessing(config config.Config, requestID, deviceID string, isPrefetch bool, action string, startTime time.Time) {
-	if !config.Kiosk.DebugVerbose {
-		return
+// processBackgroundColor computes a CSS background from the image colours for the
+// dominant-color, gradient and matte background styles.
+// It returns an empty string when the blurred image background should be used instead.
+func processBackgroundColor(img image.Image, requestConfig config.Config, requestID, deviceID string, isPrefetch bool) string {
+	if !requestConfig.BackgroundBlur || strings.EqualFold(requestConfig.ImageFit, "cover") {
+		return ""
 	}
 
-	duration := time.Since(startTime).Seconds()
-	if isPrefetch {
-		log.Debug(requestID, "PREFETCH", deviceID, action+" image in", duration)
-	} else {
-		log.Debug(requestID, action+" image in", duration)
-	}
-}
+	startTime := time.Now()
 
-// trimHistory ensures that the history slice doesn't exceed the specified maximum length.
-func trimHistory(history *[]string, maxLength int) {
-	if len(*history) > maxLength {
-		*history = (*history)[len(*history)-maxLength:]
+	var background string
+
+	switch requestConfig.BackgroundStyle {
+	case config.BackgroundStyleDominantColor:
+		background = utils.DominantColor(img).RGB
+	case config.BackgroundStyleGradient:
+		background = edgeGradient(img)
+	case config.BackgroundStyleMatte:
+		background = utils.MatteColor(img).RGB
+	default:
+		return ""
 	}
+
+	logImageProcessing(requestConfig, requestID, deviceID, isPrefetch, "Background colour", startTime)
+
+	return background
 }
 
-func DrawFaceOnImage(img image.Image, i *immich.ImmichAsset) image.Image {
+// edgeGradient builds a CSS gradient from the colours along the edges the background will show on.
+// Portrait images leave space to their sides so the left and right edges are used,
+// otherwise the top and bottom edges are used.
+func edgeGradient(img image.Image) string {
+	top, bottom, left, right := utils.EdgeColors(img)
 
-	if len(i.People) == 0 && len(i.UnassignedFaces) == 0 {
-		log.Debug("no people found")
-		return img
+	bounds := img.Bounds()
+	if bounds.Dy() > bounds.Dx() {
+		return fmt.Sprintf("linear-gradient(to right, %s, %s)", left.RGB, right.RGB)
 	}
 
-	dc := gg.NewContext(img.Bounds().Dx(), img.Bounds().Dy())
+	return fmt.Sprintf("linear-gradient(to bottom, %s, %s)", top.RGB, bottom.RGB)
+}
 
-	dc.DrawImage(img, 0, 0)
+// applyImageFilters applies the configured server-side image filters in order.
+// Unknown filters are skipped with a warning.
+func applyImageFilters(img image.Image, requestConfig config.Config, requestID, deviceID string, isPrefetch bool) image.Image {
+	if len(requestConfig.ImageFilters) == 0 {
+		return img
+	}
 
-	for _, person := range i.People {
-		for _, face := range person.Faces {
-			width := face.BoundingBoxX2 - face.BoundingBoxX1
-			height := face.BoundingBoxY2 - face.BoundingBoxY1
+	startTime := time.Now()
 
-			dc.DrawRectangle(float64(face.BoundingBoxX1), float64(face.BoundingBoxY1), float64(width), float64(height))
-			dc.SetHexColor("#990000")
-			dc.Fill()
+	for _, filter := range requestConfig.ImageFilters {
+		switch strings.ToLower(filter) {
+		case config.ImageFilterBlackAndWhite:
+			img = imaging.Grayscale(img)
+		case config.ImageFilterSepia:
+			img = utils.SepiaImage(img)
+		case config.ImageFilterVintage:
+			img = utils.VintageImage(img)
+		case config.ImageFilterVignette:
+			img = utils.VignetteImage(img, 0.6)
+		case config.ImageFilterAutoContrast:
+			img = utils.AutoContrastImage(img)
+		case config.ImageFilterWarm:
+			img = utils.WarmImage(img)
+		default:
+			log.Warn("Unknown image filter", "filter", filter)
 		}
 	}
 
-	for _, face := range i.UnassignedFaces {
-		width := face.BoundingBoxX2 - face.BoundingBoxX1
-		height := face.BoundingBoxY2 - face.BoundingBoxY1
+	logImageProcessing(requestConfig, requestID, deviceID, isPrefetch, "Filtered", startTime)
 
-		dc.DrawRectangle(float64(face.BoundingBoxX1), float64(face.BoundingBoxY1), float64(width), float64(height))
-		dc.SetHexColor("#000099")
-		dc.Fill()
-	}
+	return img
+}
 
-	facesBoundX, facesBoundY := i.FacesCenterPointPX()
-	dc.DrawRectangle(facesBoundX-10, facesBoundY-10, 20, 20)
-	dc.SetHexColor("#889900")
-	dc.Fill()
+// logImageProcessing logs the time taken for image processing if debug verbose is enabled.
+func logImageProcessing(config config.Config, requestID, deviceID string, isPrefetch bool, action string, startTime time.Time) {
+	if !config.Kiosk.DebugVerbose {
+		return
+	}
 
-	return dc.Image()
+	duration := time.Since(startTime).Seconds()
+	if isPrefetch {
+		log.Debug(requestID, "PREFETCH", deviceID, action+" image in", duration)
+	} else {
+		log.Debug(requestID, action+" image in", duration)
+	}
+}
 
+// trimHistory ensures that the history slice doesn't exceed the specified maximum length.
+func trimHistory(history *[]string, maxLength int) {
+	if len(*history) > maxLength {
+		*history = (*history)[len(*history)-maxLength:]
+	}
 }
 
 // processViewImageData handles the entire process of preparing page data including image processing.
@@ -314,23 +495,63 @@ func processViewImageData(imageOrientation immich.ImageOrientation, requestConfi
 		return common.ViewImageData{}, fmt.Errorf("selecting image: %w", err)
 	}
 
-	if strings.EqualFold(requestConfig.ImageEffect, "smart-zoom") && len(immichImage.People)+len(immichImage.UnassignedFaces) == 0 {
-		immichImage.CheckForFaces(requestID, deviceID)
+	return buildViewImageData(img, immichImage, requestConfig, requestID, deviceID, isPrefetch)
+}
+
+// processAssetViewImageData prepares a specific asset for display, e.g. a previous asset or one a device was remotely asked to show.
+// The asset info is fetched first, as the rendition, orientation and panorama checks rely on it.
+func processAssetViewImageData(assetID string, requestConfig config.Config, requestID, deviceID string) (common.ViewImageData, error) {
+	immichImage := immich.NewImage(requestConfig)
+	immichImage.ID = assetID
+
+	if err := immichImage.AssetInfo(requestID, deviceID); err != nil {
+		return common.ViewImageData{}, err
+	}
+
+	img, err := fetchImagePreview(&immichImage, requestID, deviceID, false)
+	if err != nil {
+		return common.ViewImageData{}, err
 	}
 
-	if ShouldDrawFacesOnImages() {
-		log.Debug("Drawing faces")
-		img = DrawFaceOnImage(img, &immichImage)
+	img, err = applyPrivacyBlur(img, &immichImage, requestConfig, requestID, deviceID, false)
+	if err != nil {
+		return common.ViewImageData{}, err
 	}
 
-	if requestConfig.OptimizeImages {
+	return buildViewImageData(img, immichImage, requestConfig, requestID, deviceID, false)
+}
+
+// buildViewImageData applies effects, filters and backgrounds to a retrieved image and encodes it for the view.
+func buildViewImageData(img image.Image, immichImage immich.ImmichAsset, requestConfig config.Config, requestID, deviceID string, isPrefetch bool) (common.ViewImageData, error) {
+	var err error
+
+	isPanorama := isPanoramaSlide(&immichImage, requestConfig)
+	if isPanorama {
+		// panoramas fill the frame height and scroll, so backgrounds and zoom effects are not needed
+		requestConfig.BackgroundBlur = false
+		requestConfig.ImageEffect = ""
+	}
+
+	usesFaces := strings.EqualFold(requestConfig.ImageEffect, "smart-zoom") || strings.EqualFold(requestConfig.ImageEffect, "ken-burns")
+	if usesFaces && len(immichImage.People)+len(immichImage.UnassignedFaces) == 0 {
+		immichImage.CheckForFaces(requestID, deviceID)
+	}
+
+	switch {
+	case isPanorama:
+		if height := requestConfig.ClientData.Height; height > 0 && img.Bounds().Dy() > height {
+			img = imaging.Resize(img, 0, height, imaging.Lanczos)
+		}
+	case requestConfig.OptimizeImages:
 		img, err = utils.OptimizeImage(img, requestConfig.ClientData.Width, requestConfig.ClientData.Height)
 		if err != nil {
 			return common.ViewImageData{}, err
 		}
 	}
 
-	imgString, err := imageToBase64(img, requestConfig, requestID, deviceID, "Converted", isPrefetch)
+	img = applyImageFilters(img, requestConfig, requestID, deviceID, isPrefetch)
+
+	imgString, err := imageToBase64(debugOverlay(img, &immichImage, requestConfig, requestID, deviceID), requestConfig.ImageFormat, requestConfig.ImageQuality, requestConfig, requestID, deviceID, "Converted", isPrefetch)
 	if err != nil {
 		return common.ViewImageData{}, err
 	}
@@ -340,11 +561,23 @@ func processViewImageData(imageOrientation immich.ImageOrientation, requestConfi
 		return common.ViewImageData{}, err
 	}
 
-	return common.ViewImageData{
-		ImmichImage:   immichImage,
-		ImageData:     imgString,
-		ImageBlurData: imgBlurString,
-	}, nil
+	viewImageData := common.ViewImageData{
+		ImmichImage:     immichImage,
+		ImageData:       imgString,
+		ImageBlurData:   imgBlurString,
+		ImageBackground: processBackgroundColor(img, requestConfig, requestID, deviceID, isPrefetch),
+	}
+
+	if strings.EqualFold(requestConfig.ImageEffect, "ken-burns") {
+		viewImageData.ImageKenBurns = kenBurnsPath(&immichImage, requestConfig)
+	}
+
+	if isPanorama {
+		viewImageData.ImagePanorama = true
+		viewImageData.ImageDuration = panoramaDuration(img.Bounds().Dx(), img.Bounds().Dy(), requestConfig)
+	}
+
+	return viewImageData, nil
 }
 
 func ProcessViewImageData(requestConfig config.Config, c echo.Context, isPrefetch bool) (common.ViewImageData, error) {
@@ -423,8 +656,6 @@ func renderCachedViewData(c echo.Context, cachedViewData []common.ViewData, requ
 // generateViewData generates page data for the current request.
 func generateViewData(requestConfig config.Config, c echo.Context, deviceID string, isPrefetch bool) (common.ViewData, error) {
 
-	const maxImageRetrievalAttepmts = 3
-
 	viewData := common.ViewData{
 		DeviceID: deviceID,
 		Config:   requestConfig,
@@ -490,6 +721,13 @@ func generateViewData(requestConfig config.Config, c echo.Context, deviceID stri
 			}
 		}
 
+	case "grid-3", "grid-4", "mosaic":
+		viewDataCollage, err := processCollage(collageLayouts[requestConfig.Layout], requestConfig, c, isPrefetch)
+		if err != nil {
+			return viewData, err
+		}
+		viewData.Images = append(viewData.Images, viewDataCollage...)
+
 	default:
 		viewDataSingle, err := ProcessViewImageData(requestConfig, c, isPrefetch)
 		if err != nil {
@@ -500,3 +738,88 @@ func generateViewData(requestConfig config.Config, c echo.Context, deviceID stri
 
 	return viewData, nil
 }
+
+// generateAssetViewData builds the view data for a single, specific asset.
+// Used when a device has been remotely asked to show an asset, whatever its layout.
+func generateAssetViewData(assetID string, requestConfig config.Config, requestID, deviceID string) (common.ViewData, error) {
+	viewData := common.ViewData{
+		DeviceID: deviceID,
+		Config:   requestConfig,
+	}
+
+	viewDataAsset, err := processAssetViewImageData(assetID, requestConfig, requestID, deviceID)
+	if err != nil {
+		return viewData, err
+	}
+	viewData.Images = append(viewData.Images, viewDataAsset)
+
+	return viewData, nil
+}
+
+// processCollage fetches one image per slot in parallel, each matching the slot's orientation.
+// All images are returned together so they are recorded as a single history entry.
+func processCollage(slots []immich.ImageOrientation, requestConfig config.Config, c echo.Context, isPrefetch bool) ([]common.ViewImageData, error) {
+	return fillCollage(c.Request().Context(), slots, func(orientation immich.ImageOrientation) (common.ViewImageData, error) {
+		return ProcessViewImageDataWithRatio(orientation, requestConfig, c, isPrefetch)
+	})
+}
+
+// fillCollage fills each slot with an image from fetch in parallel.
+// Duplicate assets (which can occur when parallel requests race on the same cached asset list)
+// are replaced sequentially with another asset of the slot's orientation.
+// If a slot cannot be filled with a unique asset, the remaining images would land in tiles
+// meant for other orientations, so only the first image is returned and shown on its own.
+func fillCollage(ctx context.Context, slots []immich.ImageOrientation, fetch func(immich.ImageOrientation) (common.ViewImageData, error)) ([]common.ViewImageData, error) {
+
+	images := make([]common.ViewImageData, len(slots))
+
+	g, _ := errgroup.WithContext(ctx)
+
+	for i, orientation := range slots {
+		i, orientation := i, orientation
+		g.Go(func() error {
+			viewImageData, err := fetch(orientation)
+			if err != nil {
+				return err
+			}
+			images[i] = viewImageData
+			return nil
+		})
+	}
+
+	if err := g.Wait(); err != nil {
+		return nil, err
+	}
+
+	seen := make(map[string]struct{}, len(images))
+	collage := make([]common.ViewImageData, 0, len(images))
+
+	for i, viewImageData := range images {
+		if _, duplicate := seen[viewImageData.ImmichImage.ID]; duplicate {
+			replaced := false
+
+			for attempt := 0; attempt < maxImageRetrievalAttepmts; attempt++ {
+				replacement, err := fetch(slots[i])
+				if err != nil {
+					return collage, err
+				}
+
+				if _, duplicate := seen[replacement.ImmichImage.ID]; !duplicate {
+					viewImageData = replacement
+					replaced = true
+					break
+				}
+			}
+
+			if !replaced {
+				log.Debug("unable to find unique image for collage slot, showing a single image", "slot", i, "orientation", slots[i])
+				return collage[:1], nil
+			}
+		}
+
+		seen[viewImageData.ImmichImage.ID] = struct{}{}
+		collage = append(collage, viewImageData)
+	}
+
+	return collage, nil
+}
@@ -0,0 +1,68 @@
+package routes
+
+import (
+	"hash/crc32"
+
+	"github.com/damongolding/immich-kiosk/internal/common"
+	"github.com/damongolding/immich-kiosk/internal/config"
+	"github.com/damongolding/immich-kiosk/internal/immich"
+)
+
+const (
+	// kenBurnsMinScale is the smallest zoom used so there is always some movement
+	kenBurnsMinScale = 1.1
+	// kenBurnsFaceCoverage is the share of the viewport the group of faces should fill when zoomed in
+	kenBurnsFaceCoverage = 50.0
+)
+
+// kenBurnsPath computes the pan and zoom path for the ken-burns effect.
+// When faces are detected the path moves between the group of faces and the whole image,
+// otherwise it pans across the image along its longest side.
+// The direction is derived from the asset ID so prefetched and re-rendered slides animate the same way.
+func kenBurnsPath(immichImage *immich.ImmichAsset, requestConfig config.Config) common.KenBurnsPath {
+	maxScale := max(kenBurnsMinScale, float64(requestConfig.ImageEffectAmount)/100)
+	reverse := crc32.ChecksumIEEE([]byte(immichImage.ID))%2 == 1
+
+	var path common.KenBurnsPath
+
+	if x1, y1, x2, y2, ok := immichImage.FacesBoundingBox(); ok {
+		faceSpan := max(x2-x1, y2-y1)
+		scale := maxScale
+		if faceSpan > 0 {
+			scale = min(maxScale, max(kenBurnsMinScale, kenBurnsFaceCoverage/faceSpan))
+		}
+
+		faces := clampKenBurnsViewport(common.KenBurnsViewport{Scale: scale, X: (x1 + x2) / 2, Y: (y1 + y2) / 2})
+		scenery := common.KenBurnsViewport{Scale: 1, X: 50, Y: 50}
+
+		// zoom out from the faces to the scenery, or reversed zoom in to the faces
+		path = common.KenBurnsPath{Start: faces, End: scenery}
+	} else {
+		start := common.KenBurnsViewport{Scale: maxScale, X: 0, Y: 50}
+		end := common.KenBurnsViewport{Scale: maxScale, X: 100, Y: 50}
+
+		if immichImage.IsPortrait {
+			start.X, start.Y = 50, 0
+			end.X, end.Y = 50, 100
+		}
+
+		path = common.KenBurnsPath{Start: clampKenBurnsViewport(start), End: clampKenBurnsViewport(end)}
+	}
+
+	if reverse {
+		path.Start, path.End = path.End, path.Start
+	}
+
+	return path
+}
+
+// clampKenBurnsViewport keeps a viewport inside the image so no empty space is revealed.
+func clampKenBurnsViewport(viewport common.KenBurnsViewport) common.KenBurnsViewport {
+	viewport.Scale = max(1, viewport.Scale)
+
+	half := 50 / viewport.Scale
+	viewport.X = min(max(viewport.X, half), 100-half)
+	viewport.Y = min(max(viewport.Y, half), 100-half)
+
+	return viewport
+}
@@ -0,0 +1,377 @@
+package routes
+
+import (
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+
+	"github.com/damongolding/immich-kiosk/internal/config"
+	"github.com/damongolding/immich-kiosk/internal/devices"
+	"github.com/damongolding/immich-kiosk/internal/openapi"
+	"github.com/damongolding/immich-kiosk/internal/utils"
+)
+
+const (
+	tagSlideshow = "Slideshow"
+	tagAPI       = "API"
+	tagWebhooks  = "Webhooks"
+	tagAdmin     = "Admin"
+	tagAuth      = "Auth"
+
+	mimeHTML = "text/html"
+	mimeJSON = "application/json"
+)
+
+// OpenAPI returns the OpenAPI document describing Kiosk's HTTP API
+func OpenAPI(c echo.Context) error {
+	return c.JSON(http.StatusOK, OpenAPISpec())
+}
+
+// OpenAPISpec builds the OpenAPI document for every route Kiosk serves, apart from its static assets.
+// Its version is the Kiosk version, so the document changes with each release.
+func OpenAPISpec() *openapi.Document {
+	doc := openapi.New(openapi.Info{
+		Title:       "Immich Kiosk",
+		Description: "The routes used by the Kiosk frontend, and the JSON API under /api/v1 for other apps.",
+		Version:     KioskVersion,
+	})
+
+	doc.Components.SecuritySchemes["password"] = openapi.SecurityScheme{Type: "apiKey", Name: "password", In: "query"}
+	doc.Components.SecuritySchemes["token"] = openapi.SecurityScheme{Type: "apiKey", Name: "token", In: "query"}
+	doc.Components.SecuritySchemes["bearer"] = openapi.SecurityScheme{Type: "http", Scheme: "bearer"}
+	doc.Components.SecuritySchemes["session"] = openapi.SecurityScheme{Type: "apiKey", Name: sessionCookie, In: "cookie"}
+	doc.Components.SecuritySchemes["admin"] = openapi.SecurityScheme{Type: "http", Scheme: "basic"}
+
+	// credentials are only needed once a password is set or a token is created
+	doc.Security = []openapi.SecurityRequirement{{"password": {}}, {"token": {}}, {"bearer": {}}, {"session": {}}, {"admin": {}}, {}}
+
+	// admin routes need the admin password, and are forbidden until it is set
+	admin := []openapi.SecurityRequirement{{"admin": {}}}
+	forbidden := openapi.Response{Description: "No admin password is set"}
+
+	// every setting that can be set in the URL
+	settings := append([]openapi.Parameter{
+		{Name: "client", In: "query", Description: "Client profile to use", Schema: &openapi.Schema{Type: "string"}},
+	}, doc.QueryParameters(config.Config{})...)
+
+	html := map[string]openapi.MediaType{mimeHTML: {}}
+	noContent := openapi.Response{Description: "Nothing to show"}
+
+	doc.Add(http.MethodGet, "/", openapi.Operation{
+		OperationID: "home",
+		Summary:     "The slideshow page",
+		Tags:        []string{tagSlideshow},
+		Parameters:  settings,
+		Responses:   map[string]openapi.Response{"200": {Description: "The slideshow", Content: html}},
+	})
+
+	doc.Add(http.MethodGet, "/assets/manifest.json", openapi.Operation{
+		OperationID: "manifest",
+		Summary:     "The web app manifest, pointing at the page it was requested from",
+		Tags:        []string{tagSlideshow},
+		Responses: map[string]openapi.Response{
+			"200": {Description: "The manifest", Content: map[string]openapi.MediaType{"application/manifest+json": {}}},
+		},
+	})
+
+	doc.Add(http.MethodGet, "/image", openapi.Operation{
+		OperationID: "rawImage",
+		Summary:     "A random image",
+		Description: "Returns the image without the slideshow's UI. The format is picked from the Accept header, or by eink_format for e-ink displays.",
+		Tags:        []string{tagSlideshow},
+		Parameters:  settings,
+		Responses: map[string]openapi.Response{
+			"200": {Description: "The image", Content: rawImageContent()},
+		},
+	})
+
+	doc.Add(http.MethodGet, "/frame.jpg", openapi.Operation{
+		OperationID: "frameImage",
+		Summary:     "A random image sized for a digital photo frame",
+		Tags:        []string{tagSlideshow},
+		Parameters:  settings,
+		Responses: map[string]openapi.Response{
+			"200": {Description: "The image", Content: map[string]openapi.MediaType{utils.ImageFormatMimeType(utils.ImageFormatJPEG): {}}},
+			"400": {Description: "The frame is larger than max_image_megapixels"},
+		},
+	})
+
+	doc.Add(http.MethodPost, "/image", openapi.Operation{
+		OperationID: "nextImage",
+		Summary:     "The next slide",
+		Tags:        []string{tagSlideshow},
+		Parameters:  settings,
+		Responses:   map[string]openapi.Response{"200": {Description: "The slide", Content: html}, "204": noContent},
+	})
+
+	doc.Add(http.MethodPost, "/image/previous", openapi.Operation{
+		OperationID: "previousImage",
+		Summary:     "The previous slide",
+		Tags:        []string{tagSlideshow},
+		Parameters:  settings,
+		Responses:   map[string]openapi.Response{"200": {Description: "The slide", Content: html}, "204": noContent},
+	})
+
+	doc.Add(http.MethodGet, "/clock", openapi.Operation{
+		OperationID: "clock",
+		Summary:     "The clock",
+		Tags:        []string{tagSlideshow},
+		Parameters:  settings,
+		Responses:   map[string]openapi.Response{"200": {Description: "The clock", Content: html}},
+	})
+
+	doc.Add(http.MethodGet, "/weather", openapi.Operation{
+		OperationID: "weather",
+		Summary:     "The weather for a location",
+		Tags:        []string{tagSlideshow},
+		Parameters: []openapi.Parameter{
+			{Name: "weather", In: "query", Description: "Weather location name, the default location if not set", Schema: &openapi.Schema{Type: "string"}},
+		},
+		Responses: map[string]openapi.Response{"200": {Description: "The weather", Content: html}, "204": noContent},
+	})
+
+	doc.Add(http.MethodGet, "/sleep", openapi.Operation{
+		OperationID: "sleep",
+		Summary:     "Whether the slideshow should be asleep",
+		Tags:        []string{tagSlideshow},
+		Parameters:  settings,
+		Responses:   map[string]openapi.Response{"200": {Description: "The sleep controller", Content: html}},
+	})
+
+	doc.Add(http.MethodPost, "/cache/flush", openapi.Operation{
+		OperationID: "flushCache",
+		Summary:     "Flush the cached API responses and prefetched slides",
+		Tags:        []string{tagSlideshow},
+		Responses:   map[string]openapi.Response{"204": {Description: "Flushed"}},
+	})
+
+	doc.Add(http.MethodPost, "/refresh/check", openapi.Operation{
+		OperationID: "refreshCheck",
+		Summary:     "Check whether the page should reload or run remote commands",
+		Tags:        []string{tagSlideshow},
+		Responses:   map[string]openapi.Response{"204": {Description: "Instructions are sent in the HX-Refresh and HX-Trigger headers"}},
+	})
+
+	doc.Add(http.MethodGet, "/events", openapi.Operation{
+		OperationID: "events",
+		Summary:     "Push reloads, remote commands and sleep changes to the device",
+		Tags:        []string{tagSlideshow},
+		Parameters: []openapi.Parameter{
+			{Name: "version", In: "query", Description: "Kiosk version the page was rendered with", Schema: &openapi.Schema{Type: "string"}},
+			{Name: "reload", In: "query", Description: "When the config the page was rendered with was loaded", Schema: &openapi.Schema{Type: "string"}},
+		},
+		Responses: map[string]openapi.Response{
+			"200": {Description: "A stream of reload, commands and sleep events", Content: map[string]openapi.MediaType{"text/event-stream": {}}},
+		},
+	})
+
+	doc.Add(http.MethodPost, "/webhooks", openapi.Operation{
+		OperationID: "webhook",
+		Summary:     "Trigger a user webhook event from the slideshow",
+		Tags:        []string{tagWebhooks},
+		Parameters: []openapi.Parameter{
+			{Name: "X-Timestamp", In: "header", Required: true, Schema: &openapi.Schema{Type: "string"}},
+			{Name: "X-Signature", In: "header", Required: true, Schema: &openapi.Schema{Type: "string"}},
+			{Name: "kiosk-webhook-event", In: "header", Required: true, Schema: &openapi.Schema{Type: "string"}},
+		},
+		Responses: map[string]openapi.Response{
+			"200": {Description: "Triggered"},
+			"204": {Description: "Unknown event"},
+			"400": {Description: "Invalid request"},
+			"403": {Description: "Invalid signature"},
+		},
+	})
+
+	assets := map[string]openapi.MediaType{mimeJSON: {Schema: doc.Schema(ApiResponse{})}}
+
+	doc.Add(http.MethodGet, "/api/v1/next", openapi.Operation{
+		OperationID: "apiNext",
+		Summary:     "The next assets, with their metadata",
+		Tags:        []string{tagAPI},
+		Parameters:  settings,
+		Responses:   map[string]openapi.Response{"200": {Description: "The assets", Content: assets}, "204": noContent},
+	})
+
+	doc.Add(http.MethodGet, "/api/v1/previous", openapi.Operation{
+		OperationID: "apiPrevious",
+		Summary:     "The previously shown assets, with their metadata",
+		Tags:        []string{tagAPI},
+		Parameters: append([]openapi.Parameter{
+			{Name: "history", In: "query", Description: "The history returned by the last call", Schema: &openapi.Schema{Type: "array", Items: &openapi.Schema{Type: "string"}}},
+		}, settings...),
+		Responses: map[string]openapi.Response{"200": {Description: "The assets", Content: assets}, "204": noContent},
+	})
+
+	command := doc.Schema(devices.Command{})
+	actions := make([]string, 0, len(devices.Actions))
+	for _, action := range devices.Actions {
+		actions = append(actions, string(action))
+	}
+	doc.Components.Schemas["Command"].Properties["action"].Enum = actions
+
+	commandBody := &openapi.RequestBody{Required: true, Content: map[string]openapi.MediaType{mimeJSON: {Schema: command}}}
+	commandSent := map[string]openapi.Response{
+		"202": {Description: "The command was sent", Content: map[string]openapi.MediaType{mimeJSON: {Schema: doc.Schema(RemoteCommandResponse{})}}},
+		"400": {Description: "Invalid command"},
+		"403": forbidden,
+		"404": {Description: "No matching devices"},
+	}
+
+	doc.Add(http.MethodPost, "/api/v1/devices/:id/command", openapi.Operation{
+		OperationID: "deviceCommand",
+		Summary:     "Send a command to a device",
+		Tags:        []string{tagAPI},
+		Security:    admin,
+		RequestBody: commandBody,
+		Responses:   commandSent,
+	})
+
+	doc.Add(http.MethodPost, "/api/v1/clients/:name/command", openapi.Operation{
+		OperationID: "clientCommand",
+		Summary:     "Send a command to every device using a client profile",
+		Tags:        []string{tagAPI},
+		Security:    admin,
+		RequestBody: commandBody,
+		Responses:   commandSent,
+	})
+
+	doc.Add(http.MethodGet, "/api/openapi.json", openapi.Operation{
+		OperationID: "openAPI",
+		Summary:     "This document",
+		Tags:        []string{tagAPI},
+		Responses:   map[string]openapi.Response{"200": {Description: "The OpenAPI document", Content: map[string]openapi.MediaType{mimeJSON: {}}}},
+	})
+
+	public := []openapi.SecurityRequirement{{}}
+
+	doc.Add(http.MethodGet, "/login", openapi.Operation{
+		OperationID: "login",
+		Summary:     "The login page",
+		Tags:        []string{tagAuth},
+		Security:    public,
+		Parameters: []openapi.Parameter{
+			{Name: "redirect", In: "query", Description: "Where to go once logged in", Schema: &openapi.Schema{Type: "string"}},
+		},
+		Responses: map[string]openapi.Response{"200": {Description: "The login page", Content: html}},
+	})
+
+	doc.Add(http.MethodPost, "/login", openapi.Operation{
+		OperationID: "loginSubmit",
+		Summary:     "Log in with the password or a token, starting a session",
+		Tags:        []string{tagAuth},
+		Security:    public,
+		RequestBody: &openapi.RequestBody{Required: true, Content: map[string]openapi.MediaType{
+			"application/x-www-form-urlencoded": {Schema: &openapi.Schema{Type: "object", Properties: map[string]*openapi.Schema{
+				"secret":   {Type: "string"},
+				"redirect": {Type: "string"},
+				"_csrf":    {Type: "string"},
+			}}},
+		}},
+		Responses: map[string]openapi.Response{
+			"303": {Description: "Logged in, sets the session cookie and redirects"},
+			"401": {Description: "Incorrect password or token", Content: html},
+			"429": {Description: "Too many failed attempts", Content: html},
+		},
+	})
+
+	doc.Add(http.MethodPost, "/logout", openapi.Operation{
+		OperationID: "logout",
+		Summary:     "End the session",
+		Tags:        []string{tagAuth},
+		Security:    public,
+		RequestBody: &openapi.RequestBody{Required: true, Content: map[string]openapi.MediaType{
+			"application/x-www-form-urlencoded": {Schema: &openapi.Schema{Type: "object", Properties: map[string]*openapi.Schema{
+				"_csrf": {Type: "string"},
+			}}},
+		}},
+		Responses: map[string]openapi.Response{"303": {Description: "Logged out, redirects to the login page"}},
+	})
+
+	done := map[string]openapi.Response{"303": {Description: "Redirects back to the dashboard"}}
+
+	doc.Add(http.MethodGet, "/admin", openapi.Operation{
+		OperationID: "admin",
+		Summary:     "The admin dashboard",
+		Tags:        []string{tagAdmin},
+		Security:    admin,
+		Responses:   map[string]openapi.Response{"200": {Description: "The dashboard", Content: html}},
+	})
+
+	doc.Add(http.MethodPost, "/admin/cache/flush", openapi.Operation{
+		OperationID: "adminFlushCache",
+		Summary:     "Flush the cache from the admin dashboard",
+		Tags:        []string{tagAdmin},
+		Security:    admin,
+		Responses:   done,
+	})
+
+	doc.Add(http.MethodPost, "/admin/devices/:id/:command", openapi.Operation{
+		OperationID: "adminDeviceCommand",
+		Summary:     "Send a command to a device from the admin dashboard",
+		Tags:        []string{tagAdmin},
+		Security:    admin,
+		Parameters: []openapi.Parameter{
+			{Name: "command", In: "path", Required: true, Schema: &openapi.Schema{Type: "string", Enum: []string{string(devices.ActionNext), string(devices.ActionReload)}}},
+		},
+		Responses: done,
+	})
+
+	doc.Add(http.MethodGet, "/admin/assets/:id/thumbnail", openapi.Operation{
+		OperationID: "adminAssetThumbnail",
+		Summary:     "The thumbnail of an asset shown on the admin dashboard",
+		Tags:        []string{tagAdmin},
+		Security:    admin,
+		Responses: map[string]openapi.Response{
+			"200": {Description: "The thumbnail", Content: map[string]openapi.MediaType{"image/*": {}}},
+		},
+	})
+
+	doc.Add(http.MethodPost, "/admin/tokens", openapi.Operation{
+		OperationID: "adminCreateToken",
+		Summary:     "Create an API token, optionally bound to a client profile and device",
+		Tags:        []string{tagAdmin},
+		Security:    admin,
+		RequestBody: &openapi.RequestBody{Required: true, Content: map[string]openapi.MediaType{
+			"application/x-www-form-urlencoded": {Schema: &openapi.Schema{Type: "object", Properties: map[string]*openapi.Schema{
+				"name":   {Type: "string"},
+				"client": {Type: "string"},
+				"device": {Type: "string"},
+				"_csrf":  {Type: "string"},
+			}}},
+		}},
+		Responses: map[string]openapi.Response{"200": {Description: "The dashboard, showing the new token once", Content: html}},
+	})
+
+	doc.Add(http.MethodPost, "/admin/tokens/:id/revoke", openapi.Operation{
+		OperationID: "adminRevokeToken",
+		Summary:     "Revoke an API token and end the sessions started with it",
+		Tags:        []string{tagAdmin},
+		Security:    admin,
+		Responses:   done,
+	})
+
+	doc.Add(http.MethodGet, "/:redirect", openapi.Operation{
+		OperationID: "redirect",
+		Summary:     "Follow a redirect from the config",
+		Tags:        []string{tagSlideshow},
+		Responses:   map[string]openapi.Response{"307": {Description: "Redirects to the configured URL, or the slideshow if there is no such redirect"}},
+	})
+
+	return doc
+}
+
+// rawImageContent lists the content types NewRawImage can respond with,
+// every format images can be encoded in plus the e-ink formats
+func rawImageContent() map[string]openapi.MediaType {
+	content := make(map[string]openapi.MediaType)
+
+	for _, format := range utils.ImageFormats {
+		content[utils.ImageFormatMimeType(format)] = openapi.MediaType{}
+	}
+
+	for _, format := range []string{config.EinkFormatPNG, config.EinkFormatRaw} {
+		content[einkMimeType(format)] = openapi.MediaType{}
+	}
+
+	return content
+}
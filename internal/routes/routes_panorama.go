@@ -0,0 +1,50 @@
+package routes
+
+import (
+	"math"
+	"slices"
+
+	"github.com/damongolding/immich-kiosk/internal/config"
+	"github.com/damongolding/immich-kiosk/internal/immich"
+)
+
+// panoramaScrollSpeed is how far a panorama scrolls each second, as a fraction of the frame height.
+// Tying the speed to the height keeps the scroll equally slow on every screen size.
+const panoramaScrollSpeed = 0.04
+
+// panoramaLayouts are the layouts that show a single image across the whole frame
+var panoramaLayouts = []string{"", "single", "landscape"}
+
+// isPanoramaSlide reports whether an image should be shown as a scrolling panorama.
+func isPanoramaSlide(immichImage *immich.ImmichAsset, requestConfig config.Config) bool {
+	if !requestConfig.PanoramaMode {
+		return false
+	}
+
+	return slices.Contains(panoramaLayouts, requestConfig.Layout) && immichImage.IsPanorama(requestConfig.PanoramaMinRatio)
+}
+
+// panoramaDuration returns how long (in seconds) a panorama of the given size should scroll for.
+// The panorama is scaled to the frame height and scrolled at panoramaScrollSpeed,
+// and is never shown for less than the refresh interval.
+func panoramaDuration(imageWidth, imageHeight int, requestConfig config.Config) int {
+	if requestConfig.PanoramaDuration > 0 {
+		return requestConfig.PanoramaDuration
+	}
+
+	frameWidth, frameHeight := frameDimensions(requestConfig.ClientData)
+
+	if imageHeight <= 0 {
+		return requestConfig.Refresh
+	}
+
+	scaledWidth := float64(imageWidth) * float64(frameHeight) / float64(imageHeight)
+	overflow := scaledWidth - float64(frameWidth)
+	if overflow <= 0 {
+		return requestConfig.Refresh
+	}
+
+	duration := int(math.Ceil(overflow / (float64(frameHeight) * panoramaScrollSpeed)))
+
+	return max(requestConfig.Refresh, duration)
+}
@@ -4,7 +4,6 @@ import (
 	"fmt"
 	"net/http"
 	"strings"
-	"sync"
 
 	"github.com/charmbracelet/log"
 	"github.com/labstack/echo/v4"
@@ -12,9 +11,8 @@ import (
 
 	"github.com/damongolding/immich-kiosk/internal/common"
 	"github.com/damongolding/immich-kiosk/internal/config"
-	"github.com/damongolding/immich-kiosk/internal/immich"
+	"github.com/damongolding/immich-kiosk/internal/devices"
 	imageComponent "github.com/damongolding/immich-kiosk/internal/templates/components/image"
-	"github.com/damongolding/immich-kiosk/internal/utils"
 	"github.com/damongolding/immich-kiosk/internal/webhooks"
 )
 
@@ -46,7 +44,7 @@ func PreviousImage(baseConfig *config.Config) echo.HandlerFunc {
 		)
 		historyLen := len(requestConfig.History)
 
-		if isSleepMode(requestConfig) || historyLen < 2 {
+		if isSleepMode(requestConfig) || devices.IsSleeping(deviceID) || historyLen < 2 {
 			return c.NoContent(http.StatusNoContent)
 		}
 
@@ -54,72 +52,47 @@ func PreviousImage(baseConfig *config.Config) echo.HandlerFunc {
 		prevImages := strings.Split(lastHistoryEntry, ",")
 		requestConfig.History = requestConfig.History[:historyLen-2]
 
-		ViewData := common.ViewData{
-			KioskVersion: KioskVersion,
-			DeviceID:     deviceID,
-			Images:       make([]common.ViewImageData, len(prevImages)),
-			Queries:      c.QueryParams(),
-			Config:       requestConfig,
+		ViewData, err := previousViewData(c, requestConfig, requestID, deviceID, prevImages)
+		if err != nil {
+			return RenderError(c, err, "processing images")
 		}
 
-		g, _ := errgroup.WithContext(c.Request().Context())
-
-		for i, imageID := range prevImages {
-			i, imageID := i, imageID
-			g.Go(func() error {
-				image := immich.NewImage(requestConfig)
-				image.ID = imageID
-
-				var wg sync.WaitGroup
-				wg.Add(1)
-
-				go func(image *immich.ImmichAsset, requestID string, wg *sync.WaitGroup) {
-					defer wg.Done()
-
-					err := image.AssetInfo(requestID, deviceID)
-					if err != nil {
-						log.Error(err)
-					}
-
-				}(&image, requestID, &wg)
-
-				imgBytes, err := image.ImagePreview()
-				if err != nil {
-					return fmt.Errorf("retrieving image: %w", err)
-				}
-
-				img, err := utils.BytesToImage(imgBytes)
-				if err != nil {
-					return err
-				}
-
-				imgString, err := imageToBase64(img, requestConfig, requestID, deviceID, "Converted", false)
-				if err != nil {
-					return fmt.Errorf("converting image to base64: %w", err)
-				}
+		go webhooks.Trigger(requestData, KioskVersion, webhooks.PreviousAsset, ViewData)
+		recordCurrentAssets(deviceID, ViewData)
+		return Render(c, http.StatusOK, imageComponent.Image(ViewData))
+	}
+}
 
-				imgBlurString, err := processBlurredImage(img, requestConfig, requestID, deviceID, false)
-				if err != nil {
-					return fmt.Errorf("converting blurred image to base64: %w", err)
-				}
+// previousViewData builds the view data for the previously shown assets with the given IDs.
+// Each asset goes through the same pipeline as a newly picked one, so going back shows it the same way.
+func previousViewData(c echo.Context, requestConfig config.Config, requestID, deviceID string, assetIDs []string) (common.ViewData, error) {
+	ViewData := common.ViewData{
+		KioskVersion: KioskVersion,
+		DeviceID:     deviceID,
+		Images:       make([]common.ViewImageData, len(assetIDs)),
+		Queries:      c.QueryParams(),
+		Config:       requestConfig,
+	}
 
-				wg.Wait()
+	g, _ := errgroup.WithContext(c.Request().Context())
 
-				ViewData.Images[i] = common.ViewImageData{
-					ImmichImage:   image,
-					ImageData:     imgString,
-					ImageBlurData: imgBlurString,
-				}
-				return nil
-			})
-		}
+	for i, imageID := range assetIDs {
+		i, imageID := i, imageID
+		g.Go(func() error {
+			viewImageData, err := processAssetViewImageData(imageID, requestConfig, requestID, deviceID)
+			if err != nil {
+				return fmt.Errorf("processing previous image %s: %w", imageID, err)
+			}
 
-		// Wait for all goroutines to complete and check for errors
-		if err := g.Wait(); err != nil {
-			return RenderError(c, err, "processing images")
-		}
+			ViewData.Images[i] = viewImageData
+			return nil
+		})
+	}
 
-		go webhooks.Trigger(requestData, KioskVersion, webhooks.PreviousAsset, ViewData)
-		return Render(c, http.StatusOK, imageComponent.Image(ViewData))
+	// Wait for all goroutines to complete and check for errors
+	if err := g.Wait(); err != nil {
+		return common.ViewData{}, err
 	}
+
+	return ViewData, nil
 }
@@ -0,0 +1,90 @@
+package routes
+
+import (
+	"fmt"
+	"image"
+	"math"
+	"time"
+
+	"github.com/damongolding/immich-kiosk/internal/config"
+	"github.com/damongolding/immich-kiosk/internal/immich"
+	"github.com/damongolding/immich-kiosk/internal/utils"
+)
+
+const (
+	// privacyFaceMargin is how much each face box is grown by (as a share of its size)
+	// so hair, ears and chins are covered too
+	privacyFaceMargin = 0.25
+)
+
+// isPrivacyBlurEnabled reports whether any faces should be blurred.
+func isPrivacyBlurEnabled(requestConfig config.Config) bool {
+	return requestConfig.BlurUnnamedFaces || len(requestConfig.BlurPeople) != 0
+}
+
+// applyPrivacyBlur blurs the faces of unnamed people and chosen people before the image is used.
+// Faces are always fetched from Immich so unassigned faces are included. If they cannot be fetched
+// an error is returned, rather than risking an unblurred image being shown.
+func applyPrivacyBlur(img image.Image, immichImage *immich.ImmichAsset, requestConfig config.Config, requestID, deviceID string, isPrefetch bool) (image.Image, error) {
+	if !isPrivacyBlurEnabled(requestConfig) {
+		return img, nil
+	}
+
+	startTime := time.Now()
+
+	if err := immichImage.FetchFaces(requestID, deviceID); err != nil {
+		return nil, fmt.Errorf("fetching faces for privacy blur: %w", err)
+	}
+
+	faces := immichImage.PrivateFaces(requestConfig.BlurUnnamedFaces, requestConfig.BlurPeople)
+	if len(faces) == 0 {
+		return img, nil
+	}
+
+	img = utils.BlurRegions(img, faceRegions(faces, img.Bounds()))
+
+	logImageProcessing(requestConfig, requestID, deviceID, isPrefetch, "Privacy blurred", startTime)
+
+	return img, nil
+}
+
+// faceRegions scales face bounding boxes to the image bounds and grows them by privacyFaceMargin.
+// Faces without the size of the image they were detected on are skipped.
+func faceRegions(faces []immich.Face, bounds image.Rectangle) []image.Rectangle {
+	regions := make([]image.Rectangle, 0, len(faces))
+
+	for _, face := range faces {
+		x1, y1, x2, y2, ok := scaleFaceBox(face, bounds)
+		if !ok {
+			continue
+		}
+
+		marginX, marginY := (x2-x1)*privacyFaceMargin, (y2-y1)*privacyFaceMargin
+
+		// round outwards so partly covered pixels are blurred too
+		region := image.Rect(
+			int(math.Floor(x1-marginX)), int(math.Floor(y1-marginY)),
+			int(math.Ceil(x2+marginX)), int(math.Ceil(y2+marginY)),
+		).Add(bounds.Min).Intersect(bounds)
+
+		if !region.Empty() {
+			regions = append(regions, region)
+		}
+	}
+
+	return regions
+}
+
+// scaleFaceBox scales a face bounding box from the image size it was detected on to the given bounds.
+// The last return value is false if the face has no detection image size.
+func scaleFaceBox(face immich.Face, bounds image.Rectangle) (float64, float64, float64, float64, bool) {
+	if face.ImageWidth == 0 || face.ImageHeight == 0 {
+		return 0, 0, 0, 0, false
+	}
+
+	scaleX := float64(bounds.Dx()) / float64(face.ImageWidth)
+	scaleY := float64(bounds.Dy()) / float64(face.ImageHeight)
+
+	return float64(face.BoundingBoxX1) * scaleX, float64(face.BoundingBoxY1) * scaleY,
+		float64(face.BoundingBoxX2) * scaleX, float64(face.BoundingBoxY2) * scaleY, true
+}
@@ -2,11 +2,13 @@ package routes
 
 import (
 	"net/http"
+	"slices"
 
 	"github.com/charmbracelet/log"
 	"github.com/labstack/echo/v4"
 
 	"github.com/damongolding/immich-kiosk/internal/config"
+	"github.com/damongolding/immich-kiosk/internal/devices"
 	"github.com/damongolding/immich-kiosk/internal/utils"
 )
 
@@ -32,6 +34,29 @@ func RefreshCheck(baseConfig *config.Config) echo.HandlerFunc {
 			"path", c.Request().URL.String(),
 		)
 
+		deviceID := deviceIDFromRequest(c)
+		if deviceID == "" {
+			return c.NoContent(http.StatusNoContent)
+		}
+
+		registerDevice(c, deviceID, c.FormValue("client"), requestConfig)
+
+		// deliver commands sent to the device since it last checked in
+		commands := devices.TakeCommands(deviceID)
+
+		if slices.ContainsFunc(commands, func(command devices.Command) bool {
+			return command.Action == devices.ActionReload
+		}) {
+			c.Response().Header().Set("HX-Refresh", "true")
+			return c.NoContent(http.StatusNoContent)
+		}
+
+		if len(commands) > 0 {
+			if err := setCommandsTrigger(c, commands); err != nil {
+				log.Error("sending commands", "device", deviceID, "err", err)
+			}
+		}
+
 		return c.NoContent(http.StatusNoContent)
 	}
 }
@@ -0,0 +1,124 @@
+package routes
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/charmbracelet/log"
+	"github.com/labstack/echo/v4"
+
+	"github.com/damongolding/immich-kiosk/internal/common"
+	"github.com/damongolding/immich-kiosk/internal/config"
+	"github.com/damongolding/immich-kiosk/internal/devices"
+	"github.com/damongolding/immich-kiosk/internal/utils"
+	"github.com/damongolding/immich-kiosk/internal/webhooks"
+)
+
+// remoteCommandsEvent is the htmx event the frontend listens for to run remote commands
+const remoteCommandsEvent = "kiosk-commands"
+
+// RemoteCommandResponse is returned once a remote command has been queued
+type RemoteCommandResponse struct {
+	Command devices.Command `json:"command"`
+	Devices []string        `json:"devices"`
+}
+
+// RemoteDeviceCommand sends a command to a single device by its ID
+func RemoteDeviceCommand(baseConfig *config.Config) echo.HandlerFunc {
+	return func(c echo.Context) error {
+
+		command, err := bindRemoteCommand(c)
+		if err != nil {
+			return err
+		}
+
+		deviceID := c.Param("id")
+		if !devices.SendCommand(deviceID, command) {
+			return echo.NewHTTPError(http.StatusNotFound, "Unknown device")
+		}
+
+		return remoteCommandSent(c, baseConfig, command, []string{deviceID})
+	}
+}
+
+// RemoteClientCommand sends a command to every device using the named client profile
+func RemoteClientCommand(baseConfig *config.Config) echo.HandlerFunc {
+	return func(c echo.Context) error {
+
+		command, err := bindRemoteCommand(c)
+		if err != nil {
+			return err
+		}
+
+		deviceIDs := devices.SendClientCommand(c.Param("name"), command)
+		if len(deviceIDs) == 0 {
+			return echo.NewHTTPError(http.StatusNotFound, "No devices are using this client")
+		}
+
+		return remoteCommandSent(c, baseConfig, command, deviceIDs)
+	}
+}
+
+// bindRemoteCommand reads and validates the command in the request body
+func bindRemoteCommand(c echo.Context) (devices.Command, error) {
+	var command devices.Command
+
+	if err := c.Bind(&command); err != nil {
+		return command, echo.NewHTTPError(http.StatusBadRequest, "Invalid command")
+	}
+
+	if err := command.Validate(); err != nil {
+		return command, echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+
+	if command.Action == devices.ActionShowAsset && !utils.IsValidUUID(command.AssetID) {
+		return command, echo.NewHTTPError(http.StatusBadRequest, "Invalid asset ID")
+	}
+
+	if command.Action != devices.ActionShowAsset {
+		command.AssetID = ""
+	}
+
+	return command, nil
+}
+
+// remoteCommandSent logs the command, triggers the remote.command webhook for each device and responds to the caller
+func remoteCommandSent(c echo.Context, baseConfig *config.Config, command devices.Command, deviceIDs []string) error {
+	requestID := utils.ColorizeRequestId(c.Response().Header().Get(echo.HeaderXRequestID))
+
+	log.Info(requestID+" Remote command sent", "action", command.Action, "asset", command.AssetID, "devices", deviceIDs)
+
+	for _, deviceID := range deviceIDs {
+		device, _ := devices.Get(deviceID)
+
+		requestData := &common.RouteRequestData{
+			RequestConfig: *baseConfig,
+			DeviceID:      deviceID,
+			RequestID:     requestID,
+			ClientName:    device.Client,
+		}
+
+		go webhooks.TriggerCommand(requestData, KioskVersion, command)
+	}
+
+	return c.JSON(http.StatusAccepted, RemoteCommandResponse{
+		Command: command,
+		Devices: deviceIDs,
+	})
+}
+
+// setCommandsTrigger passes commands to the frontend through an htmx event
+func setCommandsTrigger(c echo.Context, commands []devices.Command) error {
+	trigger, err := json.Marshal(map[string]any{
+		remoteCommandsEvent: map[string]any{
+			"commands": commands,
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	c.Response().Header().Set("HX-Trigger", string(trigger))
+
+	return nil
+}
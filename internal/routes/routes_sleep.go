@@ -8,6 +8,7 @@ import (
 	"github.com/labstack/echo/v4"
 
 	"github.com/damongolding/immich-kiosk/internal/config"
+	"github.com/damongolding/immich-kiosk/internal/devices"
 	"github.com/damongolding/immich-kiosk/internal/templates/partials"
 	"github.com/damongolding/immich-kiosk/internal/utils"
 )
@@ -38,8 +39,9 @@ func Sleep(baseConfig *config.Config) echo.HandlerFunc {
 		)
 
 		sleepTime, _ := utils.IsSleepTime(requestConfig.SleepStart, requestConfig.SleepEnd, time.Now())
+		sleeping := sleepTime || devices.IsSleeping(requestData.DeviceID)
 
-		return Render(c, http.StatusOK, partials.SleepController(sleepTime, requestData.RequestConfig.SleepIcon))
+		return Render(c, http.StatusOK, partials.SleepController(sleeping, requestData.RequestConfig.SleepIcon))
 
 	}
 }
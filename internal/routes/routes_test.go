@@ -1,12 +1,29 @@
 package routes
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
 	"net/http"
 	"net/http/httptest"
 	"os"
+	"regexp"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 
+	"github.com/damongolding/immich-kiosk/internal/auth"
+	"github.com/damongolding/immich-kiosk/internal/common"
 	"github.com/damongolding/immich-kiosk/internal/config"
+	"github.com/damongolding/immich-kiosk/internal/devices"
+	"github.com/damongolding/immich-kiosk/internal/immich"
+	"github.com/damongolding/immich-kiosk/internal/kiosk"
+	"github.com/damongolding/immich-kiosk/internal/utils"
 	"github.com/labstack/echo/v4"
 	"github.com/stretchr/testify/assert"
 )
@@ -83,3 +100,886 @@ func TestTrimHistory(t *testing.T) {
 		})
 	}
 }
+
+// TestCollageLayouts ensures each collage layout leads with a landscape slot
+// and requests the expected number of images.
+func TestCollageLayouts(t *testing.T) {
+	testCases := []struct {
+		layout        string
+		expectedSlots int
+	}{
+		{layout: "grid-3", expectedSlots: 3},
+		{layout: "grid-4", expectedSlots: 4},
+		{layout: "mosaic", expectedSlots: 5},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.layout, func(t *testing.T) {
+			slots, ok := collageLayouts[tc.layout]
+			assert.True(t, ok, "Layout should be registered as a collage")
+			assert.Len(t, slots, tc.expectedSlots, "Unexpected number of slots")
+			assert.Equal(t, immich.LandscapeOrientation, slots[0], "First slot should be landscape")
+		})
+	}
+}
+
+// TestFillCollage tests that collages replace duplicate assets, keep each slot's orientation
+// and fall back to a single image when a slot cannot be filled with a unique asset.
+func TestFillCollage(t *testing.T) {
+	slots := collageLayouts["mosaic"]
+
+	// fetch hands out the same asset to the first parallel requests, then assets built by next
+	newFetch := func(next func(n int, orientation immich.ImageOrientation) string) func(immich.ImageOrientation) (common.ViewImageData, error) {
+		var mu sync.Mutex
+		calls := 0
+
+		return func(orientation immich.ImageOrientation) (common.ViewImageData, error) {
+			mu.Lock()
+			defer mu.Unlock()
+
+			id := "duplicate"
+			if calls >= len(slots) {
+				id = next(calls, orientation)
+			}
+			calls++
+
+			var viewImageData common.ViewImageData
+			viewImageData.ImmichImage.ID = id
+			viewImageData.ImmichImage.IsLandscape = orientation == immich.LandscapeOrientation
+			viewImageData.ImmichImage.IsPortrait = orientation == immich.PortraitOrientation
+
+			return viewImageData, nil
+		}
+	}
+
+	t.Run("Duplicates replaced", func(t *testing.T) {
+		collage, err := fillCollage(context.Background(), slots, newFetch(func(n int, _ immich.ImageOrientation) string {
+			return fmt.Sprintf("asset-%d", n)
+		}))
+		assert.NoError(t, err)
+		assert.Len(t, collage, len(slots), "every slot should be filled")
+
+		ids := make(map[string]struct{}, len(collage))
+		for i, viewImageData := range collage {
+			ids[viewImageData.ImmichImage.ID] = struct{}{}
+			assert.Equal(t, slots[i] == immich.LandscapeOrientation, viewImageData.ImmichImage.IsLandscape, "slot %d has the wrong orientation", i)
+		}
+		assert.Len(t, ids, len(slots), "every asset should be unique")
+	})
+
+	t.Run("No unique assets", func(t *testing.T) {
+		collage, err := fillCollage(context.Background(), slots, newFetch(func(int, immich.ImageOrientation) string {
+			return "duplicate"
+		}))
+		assert.NoError(t, err)
+		assert.Len(t, collage, 1, "the collage should fall back to a single image")
+	})
+
+	t.Run("Slot cannot be filled", func(t *testing.T) {
+		// only portraits have unique replacements, so the second landscape slot cannot be filled
+		collage, err := fillCollage(context.Background(), slots, newFetch(func(n int, orientation immich.ImageOrientation) string {
+			if orientation == immich.LandscapeOrientation {
+				return "duplicate"
+			}
+			return fmt.Sprintf("asset-%d", n)
+		}))
+		assert.NoError(t, err)
+		if assert.Len(t, collage, 1, "images should not move into tiles meant for another orientation") {
+			assert.True(t, collage[0].ImmichImage.IsLandscape, "the first, landscape, image should be shown on its own")
+		}
+	})
+
+	t.Run("Error", func(t *testing.T) {
+		_, err := fillCollage(context.Background(), slots, func(immich.ImageOrientation) (common.ViewImageData, error) {
+			return common.ViewImageData{}, errors.New("no assets")
+		})
+		assert.Error(t, err)
+	})
+}
+
+func TestFrameDimensions(t *testing.T) {
+	testCases := []struct {
+		name           string
+		clientData     config.ClientData
+		expectedWidth  int
+		expectedHeight int
+	}{
+		{
+			name:           "No client data",
+			clientData:     config.ClientData{},
+			expectedWidth:  defaultFrameWidth,
+			expectedHeight: defaultFrameHeight,
+		},
+		{
+			name:           "Missing height",
+			clientData:     config.ClientData{Width: 800},
+			expectedWidth:  defaultFrameWidth,
+			expectedHeight: defaultFrameHeight,
+		},
+		{
+			name:           "Client data",
+			clientData:     config.ClientData{Width: 800, Height: 480},
+			expectedWidth:  800,
+			expectedHeight: 480,
+		},
+		{
+			name:           "Oversized client data",
+			clientData:     config.ClientData{Width: 20000, Height: 480},
+			expectedWidth:  maxFrameDimension,
+			expectedHeight: 480,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			width, height := frameDimensions(tc.clientData)
+			assert.Equal(t, tc.expectedWidth, width)
+			assert.Equal(t, tc.expectedHeight, height)
+		})
+	}
+}
+
+// TestFrameTooLarge tests frames larger than the decode pixel budget are refused before anything is rendered
+func TestFrameTooLarge(t *testing.T) {
+	baseConfig := config.New()
+
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodGet, "/frame.jpg?client_width=7680&client_height=7680", nil)
+	c := e.NewContext(req, httptest.NewRecorder())
+
+	err := NewFrameImage(baseConfig)(c)
+	var httpErr *echo.HTTPError
+	if assert.ErrorAs(t, err, &httpErr) {
+		assert.Equal(t, http.StatusBadRequest, httpErr.Code)
+	}
+}
+
+func TestFitFrameImage(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 400, 200))
+
+	testCases := []struct {
+		imageFit       string
+		expectedWidth  int
+		expectedHeight int
+	}{
+		{imageFit: "contain", expectedWidth: 800, expectedHeight: 400},
+		{imageFit: "cover", expectedWidth: 800, expectedHeight: 600},
+		{imageFit: "none", expectedWidth: 400, expectedHeight: 200},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.imageFit, func(t *testing.T) {
+			fitted := fitFrameImage(img, tc.imageFit, 800, 600)
+			assert.Equal(t, tc.expectedWidth, fitted.Bounds().Dx())
+			assert.Equal(t, tc.expectedHeight, fitted.Bounds().Dy())
+		})
+	}
+}
+
+func TestFrameImageLocation(t *testing.T) {
+	exif := immich.ExifInfo{
+		City:    "London",
+		State:   "England",
+		Country: "United Kingdom",
+	}
+
+	assert.Equal(t, "London, England, United Kingdom", frameImageLocation(exif, nil))
+	assert.Equal(t, "London, England", frameImageLocation(exif, []string{"united kingdom"}))
+}
+
+func TestRenderFrame(t *testing.T) {
+	c := config.New()
+	c.ShowTime = true
+	c.ShowDate = true
+	c.ShowImageID = true
+
+	img := image.NewRGBA(image.Rect(0, 0, 300, 400))
+	immichImage := immich.ImmichAsset{ID: "1234"}
+
+	frame, err := renderFrame(img, &immichImage, *c, "", 640, 480)
+	assert.NoError(t, err)
+	assert.Equal(t, image.Rect(0, 0, 640, 480), frame.Bounds())
+}
+
+func TestKenBurnsPath(t *testing.T) {
+	requestConfig := config.Config{ImageEffectAmount: 200}
+
+	t.Run("Faces", func(t *testing.T) {
+		asset := immich.ImmichAsset{
+			ID: "faces",
+			UnassignedFaces: []immich.Face{
+				{ImageWidth: 1000, ImageHeight: 1000, BoundingBoxX1: 400, BoundingBoxY1: 400, BoundingBoxX2: 500, BoundingBoxY2: 500},
+			},
+		}
+
+		path := kenBurnsPath(&asset, requestConfig)
+
+		faces, scenery := path.Start, path.End
+		if faces.Scale == 1 {
+			faces, scenery = scenery, faces
+		}
+
+		assert.Equal(t, common.KenBurnsViewport{Scale: 1, X: 50, Y: 50}, scenery, "One end of the path should show the whole image")
+		assert.InDelta(t, 2, faces.Scale, 0.001, "Zoom should be limited by image_effect_amount")
+		assert.InDelta(t, 45, faces.X, 0.001)
+		assert.InDelta(t, 45, faces.Y, 0.001)
+	})
+
+	t.Run("No faces", func(t *testing.T) {
+		asset := immich.ImmichAsset{ID: "scenery", IsLandscape: true}
+
+		path := kenBurnsPath(&asset, requestConfig)
+
+		assert.InDelta(t, 2, path.Start.Scale, 0.001)
+		assert.InDelta(t, 2, path.End.Scale, 0.001)
+		assert.ElementsMatch(t, []float64{25, 75}, []float64{path.Start.X, path.End.X}, "Landscape images should pan horizontally")
+		assert.Equal(t, path.Start.Y, path.End.Y)
+	})
+
+	t.Run("Deterministic", func(t *testing.T) {
+		asset := immich.ImmichAsset{ID: "same-asset"}
+		assert.Equal(t, kenBurnsPath(&asset, requestConfig), kenBurnsPath(&asset, requestConfig))
+	})
+
+	t.Run("Clamp", func(t *testing.T) {
+		viewport := clampKenBurnsViewport(common.KenBurnsViewport{Scale: 4, X: 0, Y: 100})
+		assert.Equal(t, common.KenBurnsViewport{Scale: 4, X: 12.5, Y: 87.5}, viewport)
+	})
+}
+
+func TestImageQualityReason(t *testing.T) {
+	requestConfig := config.Config{
+		MinSharpness:  30,
+		MinBrightness: 8,
+		MaxBrightness: 95,
+		MaxClipping:   60,
+	}
+
+	tests := []struct {
+		name    string
+		quality utils.ImageQuality
+		passes  bool
+	}{
+		{name: "Good", quality: utils.ImageQuality{Sharpness: 200, Brightness: 50, ShadowClipping: 5, HighlightClipping: 5}, passes: true},
+		{name: "Blurry", quality: utils.ImageQuality{Sharpness: 10, Brightness: 50}, passes: false},
+		{name: "Pocket shot", quality: utils.ImageQuality{Sharpness: 200, Brightness: 2}, passes: false},
+		{name: "Over exposed", quality: utils.ImageQuality{Sharpness: 200, Brightness: 98}, passes: false},
+		{name: "Clipped", quality: utils.ImageQuality{Sharpness: 200, Brightness: 50, ShadowClipping: 40, HighlightClipping: 40}, passes: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reason := imageQualityReason(tt.quality, requestConfig)
+			assert.Equal(t, tt.passes, reason == "", reason)
+		})
+	}
+}
+
+func TestImageQualityCache(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 16, 16))
+
+	first := imageQuality("quality-cache-test", img)
+
+	cached, found := cachedImageQuality("quality-cache-test")
+	assert.True(t, found, "Scores should be cached after the first analysis")
+	assert.Equal(t, first, cached)
+
+	// a different image with the same ID returns the cached scores
+	bright := image.NewRGBA(image.Rect(0, 0, 16, 16))
+	draw.Draw(bright, bright.Bounds(), image.NewUniform(color.White), image.Point{}, draw.Src)
+	assert.Equal(t, first, imageQuality("quality-cache-test", bright))
+}
+
+func TestPanoramaDuration(t *testing.T) {
+	tests := []struct {
+		name   string
+		width  int
+		height int
+		config config.Config
+		want   int
+	}{
+		{
+			name:   "Scroll longer than refresh",
+			width:  8000,
+			height: 1000,
+			config: config.Config{Refresh: 60, ClientData: config.ClientData{Width: 1920, Height: 1080}},
+			// 8640px scaled width - 1920px frame = 6720px at 43.2px/s
+			want: 156,
+		},
+		{
+			name:   "Never shorter than refresh",
+			width:  3000,
+			height: 1000,
+			config: config.Config{Refresh: 60, ClientData: config.ClientData{Width: 1920, Height: 1080}},
+			want:   60,
+		},
+		{
+			name:   "Fixed duration",
+			width:  8000,
+			height: 1000,
+			config: config.Config{Refresh: 60, PanoramaDuration: 30},
+			want:   30,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, panoramaDuration(tt.width, tt.height, tt.config))
+		})
+	}
+}
+
+func TestIsPanoramaSlide(t *testing.T) {
+	panorama := immich.ImmichAsset{ExifInfo: immich.ExifInfo{ExifImageWidth: 9000, ExifImageHeight: 2000}}
+
+	assert.False(t, isPanoramaSlide(&panorama, config.Config{Layout: "single", PanoramaMinRatio: 2.5}), "Panorama mode disabled")
+	assert.True(t, isPanoramaSlide(&panorama, config.Config{Layout: "single", PanoramaMode: true, PanoramaMinRatio: 2.5}))
+	assert.False(t, isPanoramaSlide(&panorama, config.Config{Layout: "splitview", PanoramaMode: true, PanoramaMinRatio: 2.5}), "Multi image layouts are not supported")
+}
+
+func TestNegotiateImageFormat(t *testing.T) {
+	tests := []struct {
+		name     string
+		accept   string
+		fallback string
+		expected string
+	}{
+		{"No header", "", "jpeg", "jpeg"},
+		{"No header png fallback", "", "png", "png"},
+		{"Wildcard", "*/*", "png", "png"},
+		{"Browser", "image/avif,image/webp,image/png,image/*;q=0.8,*/*;q=0.5", "jpeg", "png"},
+		{"Explicit jpeg", "image/jpeg", "png", "jpeg"},
+		{"Higher q wins", "image/png;q=0.5, image/jpeg;q=0.9", "png", "jpeg"},
+		{"Tie uses fallback", "image/png, image/jpeg", "png", "png"},
+		{"Rejected fallback", "image/jpeg;q=0, */*", "jpeg", "png"},
+		{"Nothing acceptable", "text/html", "jpeg", "jpeg"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, negotiateImageFormat(tt.accept, tt.fallback))
+		})
+	}
+}
+
+func TestFaceRegions(t *testing.T) {
+	faces := []immich.Face{
+		// detected on a 1000x500 image, so scaled by 0.5
+		{ImageWidth: 1000, ImageHeight: 500, BoundingBoxX1: 100, BoundingBoxY1: 100, BoundingBoxX2: 200, BoundingBoxY2: 200},
+		// grows past the image edges
+		{ImageWidth: 500, ImageHeight: 250, BoundingBoxX1: 0, BoundingBoxY1: 0, BoundingBoxX2: 40, BoundingBoxY2: 40},
+		// faces without image dimensions are skipped
+		{BoundingBoxX1: 10, BoundingBoxY1: 10, BoundingBoxX2: 20, BoundingBoxY2: 20},
+	}
+
+	regions := faceRegions(faces, image.Rect(0, 0, 500, 250))
+
+	assert.Equal(t, []image.Rectangle{
+		image.Rect(37, 37, 113, 113),
+		image.Rect(0, 0, 50, 50),
+	}, regions)
+
+	assert.False(t, isPrivacyBlurEnabled(config.Config{}))
+	assert.True(t, isPrivacyBlurEnabled(config.Config{BlurPeople: []string{"id"}}))
+}
+
+func TestDebugOverlay(t *testing.T) {
+	assert.False(t, isDebugOverlayEnabled(config.Config{DebugOverlay: true}), "Overlay should need debug mode")
+
+	debugConfig := config.Config{DebugOverlay: true}
+	debugConfig.Kiosk.Debug = true
+	assert.True(t, isDebugOverlayEnabled(debugConfig))
+
+	buckets := []utils.AssetWithWeighting{
+		{Asset: utils.WeightedAsset{Type: kiosk.SourcePerson, ID: "a"}, Weight: 30},
+		{Asset: utils.WeightedAsset{Type: kiosk.SourceAlbums, ID: "b"}, Weight: 90},
+	}
+	weight, total := assetWeighting(buckets[1].Asset, buckets)
+	assert.Equal(t, 90, weight)
+	assert.Equal(t, 120, total)
+
+	asset := immich.ImmichAsset{
+		ID:                 "asset-id",
+		KioskSource:        kiosk.SourceAlbums,
+		KioskSourceName:    "Holiday",
+		KioskSourceWeight:  weight,
+		KioskTotalWeight:   total,
+		KioskSelectionTime: 42 * time.Millisecond,
+		People: []immich.Person{
+			{Name: "Alice", Faces: []immich.Face{{ImageWidth: 100, ImageHeight: 100, BoundingBoxX1: 20, BoundingBoxY1: 20, BoundingBoxX2: 40, BoundingBoxY2: 40}}},
+		},
+	}
+
+	assert.Equal(t, []string{
+		"asset asset-id",
+		"source ALBUM (Holiday)",
+		"weighting 90 / 120 (75.0%)",
+		"selected in 42ms",
+		"faces 1 (1 people)",
+		"smart-zoom center 30.0%, 30.0%",
+	}, debugOverlayLines(&asset))
+
+	img := image.NewRGBA(image.Rect(0, 0, 200, 100))
+	annotated := drawDebugOverlay(img, &asset)
+	assert.Equal(t, img.Bounds(), annotated.Bounds())
+	assert.NotEqual(t, img.At(40, 20), annotated.At(40, 20), "Face box should be drawn")
+}
+
+// TestBindRemoteCommand tests which remote command bodies are accepted
+func TestBindRemoteCommand(t *testing.T) {
+	tests := []struct {
+		name    string
+		body    string
+		want    devices.Command
+		wantErr bool
+	}{
+		{name: "next", body: `{"action":"next"}`, want: devices.Command{Action: devices.ActionNext}},
+		{name: "asset ignored", body: `{"action":"pause","assetID":"0b6f0c3e-6f7a-4d59-9a55-2f1c9d7f3b10"}`, want: devices.Command{Action: devices.ActionPause}},
+		{
+			name: "show asset",
+			body: `{"action":"show_asset","assetID":"0b6f0c3e-6f7a-4d59-9a55-2f1c9d7f3b10"}`,
+			want: devices.Command{Action: devices.ActionShowAsset, AssetID: "0b6f0c3e-6f7a-4d59-9a55-2f1c9d7f3b10"},
+		},
+		{name: "invalid asset", body: `{"action":"show_asset","assetID":"../../users"}`, wantErr: true},
+		{name: "unknown action", body: `{"action":"dance"}`, wantErr: true},
+		{name: "invalid json", body: `{"action":`, wantErr: true},
+	}
+
+	e := echo.New()
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/api/v1/devices/device-1/command", strings.NewReader(tt.body))
+			req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+			c := e.NewContext(req, httptest.NewRecorder())
+
+			command, err := bindRemoteCommand(c)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, command)
+		})
+	}
+}
+
+// TestSetCommandsTrigger tests commands are passed to the frontend as an htmx event
+func TestSetCommandsTrigger(t *testing.T) {
+	e := echo.New()
+	rec := httptest.NewRecorder()
+	c := e.NewContext(httptest.NewRequest(http.MethodPost, "/refresh/check", nil), rec)
+
+	err := setCommandsTrigger(c, []devices.Command{
+		{Action: devices.ActionPause},
+		{Action: devices.ActionShowAsset, AssetID: "asset-1"},
+	})
+	assert.NoError(t, err)
+
+	assert.JSONEq(t,
+		`{"kiosk-commands":{"commands":[{"action":"pause"},{"action":"show_asset","assetID":"asset-1"}]}}`,
+		rec.Header().Get("HX-Trigger"),
+	)
+}
+
+// TestWritePushEvent tests Server-Sent Events are written in the event stream format
+func TestWritePushEvent(t *testing.T) {
+	rec := httptest.NewRecorder()
+	c := echo.New().NewContext(httptest.NewRequest(http.MethodGet, "/events", nil), rec)
+
+	err := writePushEvent(c.Response(), "sleep", map[string]bool{"sleeping": true})
+	assert.NoError(t, err)
+
+	assert.Equal(t, "event: sleep\ndata: {\"sleeping\":true}\n\n", rec.Body.String())
+	assert.True(t, rec.Flushed, "events should be flushed straight away")
+}
+
+// TestEventsReload tests a push connection from an outdated page is told to reload
+func TestEventsReload(t *testing.T) {
+	baseConfig := config.New()
+	baseConfig.ReloadTimeStamp = "2025-01-01T00:00:00Z"
+
+	req := httptest.NewRequest(http.MethodGet, "/events?version=old&reload=2025-01-01T00:00:00Z", nil)
+	req.AddCookie(&http.Cookie{Name: deviceIDCookie, Value: "device-1"})
+	rec := httptest.NewRecorder()
+	c := echo.New().NewContext(req, rec)
+
+	assert.NoError(t, Events(baseConfig)(c))
+
+	assert.Equal(t, "text/event-stream", rec.Header().Get(echo.HeaderContentType))
+	assert.Contains(t, rec.Body.String(), "event: reload\n")
+}
+
+// TestDeviceRegistration tests only device IDs Kiosk issued, or that are bound in config, are added to the registry
+func TestDeviceRegistration(t *testing.T) {
+	baseConfig := config.New()
+	baseConfig.Devices = map[string]config.DeviceBinding{"bound-device": {Name: "Kitchen"}}
+
+	e := echo.New()
+
+	newContext := func(deviceID string) echo.Context {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		if deviceID != "" {
+			req.AddCookie(&http.Cookie{Name: deviceIDCookie, Value: deviceID})
+		}
+		return e.NewContext(req, httptest.NewRecorder())
+	}
+
+	registerDevice(newContext("made-up-device"), "made-up-device", "", *baseConfig)
+	assert.False(t, devices.Known("made-up-device"), "IDs made up by clients should not be registered")
+
+	registerDevice(newContext("bound-device"), "bound-device", "", *baseConfig)
+	device, ok := devices.Get("bound-device")
+	assert.True(t, ok, "IDs bound in config should be registered")
+	assert.Equal(t, "Kitchen", device.Name)
+
+	issued, isNew := ensureDeviceID(newContext(""), baseConfig)
+	assert.True(t, isNew)
+	assert.True(t, devices.Known(issued), "issued IDs should be registered")
+
+	id, isNew := ensureDeviceID(newContext(issued), baseConfig)
+	assert.False(t, isNew)
+	assert.Equal(t, issued, id, "known devices should keep their ID")
+
+	id, isNew = ensureDeviceID(newContext("made-up-device"), baseConfig)
+	assert.True(t, isNew, "unknown IDs should be replaced")
+	assert.NotEqual(t, "made-up-device", id)
+	assert.False(t, devices.Known("made-up-device"))
+}
+
+// TestGroupSwap tests group slides are swapped in when their slot starts
+func TestGroupSwap(t *testing.T) {
+	tests := []struct {
+		name   string
+		config config.Config
+		showIn time.Duration
+		want   string
+	}{
+		{name: "No transition", config: config.Config{}, showIn: 1500 * time.Millisecond, want: "beforeend swap:1500ms"},
+		{name: "Already started", config: config.Config{Transition: "cross-fade"}, showIn: 0, want: "beforeend swap:0ms"},
+		{name: "Fade shorter than wait", config: config.Config{Transition: "fade", FadeTransitionDuration: 1}, showIn: 2 * time.Second, want: "innerHTML swap:2000ms"},
+		{name: "Fade longer than wait", config: config.Config{Transition: "fade", FadeTransitionDuration: 1.5}, showIn: 0, want: "innerHTML swap:1500ms"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, groupSwap(tt.config, tt.showIn))
+		})
+	}
+}
+
+// TestWallTile tests images are cut into tiles that cover the wall and skip the image behind bezels
+func TestWallTile(t *testing.T) {
+	tests := []struct {
+		name   string
+		bounds image.Rectangle
+		config config.Config
+		want   image.Rectangle
+	}{
+		{
+			name:   "Top left of 2x2",
+			bounds: image.Rect(0, 0, 2000, 1000),
+			config: config.Config{WallColumns: 2, WallRows: 2, WallColumn: 1, WallRow: 1},
+			want:   image.Rect(0, 0, 1000, 500),
+		},
+		{
+			name:   "Bottom right of 2x2",
+			bounds: image.Rect(0, 0, 2000, 1000),
+			config: config.Config{WallColumns: 2, WallRows: 2, WallColumn: 2, WallRow: 2},
+			want:   image.Rect(1000, 500, 2000, 1000),
+		},
+		{
+			name:   "Bezel skips the middle of the image",
+			bounds: image.Rect(0, 0, 2100, 500),
+			config: config.Config{WallColumns: 2, WallRows: 1, WallColumn: 2, WallRow: 1, WallBezel: 100},
+			want:   image.Rect(1100, 0, 2100, 500),
+		},
+		{
+			name:   "Wider image is cropped at the sides",
+			bounds: image.Rect(0, 0, 3000, 1000),
+			config: config.Config{WallColumns: 2, WallRows: 2, WallColumn: 1, WallRow: 1},
+			want:   image.Rect(500, 0, 1500, 500),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, wallTile(tt.bounds, tt.config, 1000, 500))
+		})
+	}
+}
+
+// TestNewApiResponse tests view data is converted into the JSON returned by the asset API
+func TestNewApiResponse(t *testing.T) {
+	viewData := common.ViewData{
+		Config: config.Config{ImmichUrl: "http://immich:2283", ImmichExternalUrl: "https://photos.example.com"},
+		Images: []common.ViewImageData{
+			{
+				ImmichImage: immich.ImmichAsset{
+					ID:              "asset-1",
+					Type:            immich.ImageType,
+					ExifInfo:        immich.ExifInfo{City: "Paris", Country: "France"},
+					People:          []immich.Person{{Name: "Alice"}, {Name: ""}},
+					KioskSource:     kiosk.SourceAlbums,
+					KioskSourceName: "Holidays",
+				},
+				ImageData: "data:image/jpeg;base64,AAAA",
+			},
+			{
+				ImmichImage: immich.ImmichAsset{ID: "asset-2", KioskSource: kiosk.SourcePerson, KioskSourceName: "Bob"},
+			},
+		},
+	}
+
+	res := newApiResponse(viewData, []string{"a", "b"})
+
+	assert.Len(t, res.Assets, 2)
+	assert.Equal(t, []string{"a", "b", "asset-1,asset-2"}, res.History)
+
+	asset := res.Assets[0]
+	assert.Equal(t, "asset-1", asset.ID)
+	assert.Equal(t, "Paris", asset.Location.City)
+	assert.Equal(t, []string{"Alice"}, asset.People, "unnamed people should be skipped")
+	assert.Equal(t, "Holidays", asset.Album)
+	assert.Equal(t, "data:image/jpeg;base64,AAAA", asset.ImageURL)
+	assert.Equal(t, "https://photos.example.com/photos/asset-1", asset.ImmichURL, "the external URL should be preferred")
+	assert.True(t, strings.HasPrefix(asset.QrCode, "data:image/png;base64,"))
+
+	assert.Empty(t, res.Assets[1].Album, "only album sources have an album")
+	assert.Equal(t, "Bob", res.Assets[1].SourceName)
+
+	long := make([]string, apiHistoryLength)
+	assert.Len(t, newApiResponse(viewData, long).History, apiHistoryLength, "history should be trimmed")
+}
+
+// TestOpenAPISpec tests every reference in the OpenAPI document points at a component
+func TestOpenAPISpec(t *testing.T) {
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodGet, "/api/openapi.json", nil)
+	rec := httptest.NewRecorder()
+
+	if !assert.NoError(t, OpenAPI(e.NewContext(req, rec))) {
+		return
+	}
+
+	var doc struct {
+		OpenAPI    string `json:"openapi"`
+		Components map[string]map[string]json.RawMessage
+	}
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &doc))
+	assert.Equal(t, "3.1.0", doc.OpenAPI)
+
+	refs := regexp.MustCompile(`"\$ref":"#/components/(\w+)/(\w+)"`).FindAllStringSubmatch(rec.Body.String(), -1)
+	assert.NotEmpty(t, refs)
+
+	for _, ref := range refs {
+		assert.Contains(t, doc.Components[ref[1]], ref[2], "%s %s is referenced but not defined", ref[1], ref[2])
+	}
+}
+
+// TestOpenAPIImageContent tests the documented image content types are exactly the ones the image routes can send
+func TestOpenAPIImageContent(t *testing.T) {
+	doc := OpenAPISpec()
+
+	rawImage, ok := doc.Operation(http.MethodGet, "/image")
+	assert.True(t, ok)
+
+	sent := make(map[string]bool)
+
+	accepts := []string{"", "*/*", "image/*", "image/png", "image/jpeg", "image/jpg", "image/png;q=0.5, image/jpeg", "image/jpeg;q=0.5, image/png", "text/html"}
+	for _, fallback := range utils.ImageFormats {
+		for _, accept := range accepts {
+			sent[utils.ImageFormatMimeType(negotiateImageFormat(accept, fallback))] = true
+		}
+	}
+
+	for _, format := range []string{config.EinkFormatPNG, config.EinkFormatRaw} {
+		sent[einkMimeType(format)] = true
+	}
+
+	documented := make(map[string]bool)
+	for contentType := range rawImage.Responses["200"].Content {
+		documented[contentType] = true
+	}
+
+	assert.Equal(t, sent, documented)
+
+	frameImage, ok := doc.Operation(http.MethodGet, "/frame.jpg")
+	assert.True(t, ok)
+	assert.Contains(t, frameImage.Responses["200"].Content, utils.ImageFormatMimeType(utils.ImageFormatJPEG))
+	assert.Len(t, frameImage.Responses["200"].Content, 1)
+}
+
+// TestSafeRedirect tests the login page only redirects to paths on this server
+func TestSafeRedirect(t *testing.T) {
+	testCases := map[string]string{
+		"/":                     "/",
+		"/?client=kitchen":      "/?client=kitchen",
+		"/admin":                "/admin",
+		"":                      "/",
+		"https://example.com":   "/",
+		"//example.com":         "/",
+		"/\\example.com":        "/",
+		"/login?redirect=/":     "/",
+		"javascript:alert(1)":   "/",
+		"/api/v1/next?token=ab": "/api/v1/next?token=ab",
+	}
+
+	for target, want := range testCases {
+		assert.Equal(t, want, safeRedirect(target), target)
+	}
+}
+
+// TestAuthenticate tests requests are let through with a session, token, password or admin credentials,
+// and that requests using a token are bound to its client profile and device
+func TestAuthenticate(t *testing.T) {
+	baseConfig := config.New()
+
+	e := echo.New()
+
+	// run authenticates req as a request to route
+	run := func(req *http.Request, route string) (*httptest.ResponseRecorder, echo.Context, bool, error) {
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+		c.SetPath(route)
+		called := false
+
+		err := Authenticate(baseConfig)(func(c echo.Context) error {
+			called = true
+			return c.NoContent(http.StatusOK)
+		})(c)
+
+		return rec, c, called, err
+	}
+
+	_, _, called, _ := run(httptest.NewRequest(http.MethodGet, "/clock", nil), "/clock")
+	assert.True(t, called, "nothing should be checked without a password or tokens")
+
+	baseConfig.Kiosk.Password = "1234"
+	baseConfig.Kiosk.AdminPassword = "admin-1234"
+
+	rec, _, called, _ := run(httptest.NewRequest(http.MethodGet, "/clock", nil), "/clock")
+	assert.False(t, called)
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+
+	_, _, called, _ = run(httptest.NewRequest(http.MethodGet, "/clock?password=1234", nil), "/clock")
+	assert.True(t, called, "the password param should be accepted")
+
+	_, _, called, _ = run(httptest.NewRequest(http.MethodGet, "/clock?password=admin-1234", nil), "/clock")
+	assert.False(t, called, "the admin password should not be accepted as the password")
+
+	req := httptest.NewRequest(http.MethodGet, "/clock", nil)
+	req.SetBasicAuth("admin", "admin-1234")
+	_, _, called, _ = run(req, "/clock")
+	assert.True(t, called, "the admin credentials should be accepted")
+
+	_, _, called, _ = run(httptest.NewRequest(http.MethodGet, "/assets/css/kiosk.css", nil), "/assets*")
+	assert.True(t, called, "assets should be public")
+
+	_, _, called, _ = run(httptest.NewRequest(http.MethodGet, "/admin/tokens", nil), "/admin/tokens")
+	assert.True(t, called, "the admin dashboard should be left to its own authentication")
+
+	_, _, called, _ = run(httptest.NewRequest(http.MethodGet, "/adminfoo", nil), "/:redirect")
+	assert.False(t, called, "redirects should not be public because they start with /admin")
+
+	req = httptest.NewRequest(http.MethodGet, "/?client=kitchen", nil)
+	req.Header.Set(echo.HeaderAccept, "text/html,application/xhtml+xml")
+	rec, _, _, _ = run(req, "/")
+	assert.Equal(t, http.StatusSeeOther, rec.Code, "pages should be sent to the login page")
+	assert.Equal(t, "/login?redirect=%2F%3Fclient%3Dkitchen", rec.Header().Get(echo.HeaderLocation))
+
+	req = httptest.NewRequest(http.MethodPost, "/image", nil)
+	req.Header.Set("HX-Request", "true")
+	req.Header.Set("HX-Current-URL", "http://kiosk.local/?client=kitchen")
+	rec, _, _, _ = run(req, "/image")
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+	assert.Equal(t, "/login?redirect=%2F%3Fclient%3Dkitchen", rec.Header().Get("HX-Redirect"), "htmx requests should send the page to the login page")
+
+	plain, token, err := auth.CreateToken("Kitchen", "kitchen", "device-1")
+	assert.NoError(t, err)
+	defer auth.RevokeToken(token.ID)
+
+	req = httptest.NewRequest(http.MethodGet, "/clock", nil)
+	req.Header.Set(echo.HeaderAuthorization, "Bearer "+plain)
+	_, c, called, _ := run(req, "/clock")
+	assert.True(t, called, "bearer tokens should be accepted")
+	assert.Equal(t, "kitchen", c.Get(authClientKey))
+	assert.Equal(t, "device-1", deviceIDFromRequest(c), "requests using a device token should be from that device")
+
+	_, _, called, _ = run(httptest.NewRequest(http.MethodGet, "/clock?token="+plain, nil), "/clock")
+	assert.True(t, called, "the token param should be accepted")
+
+	sessionID, err := auth.NewPasswordSession(kioskCredential(baseConfig), time.Now())
+	assert.NoError(t, err)
+
+	req = httptest.NewRequest(http.MethodGet, "/clock", nil)
+	req.AddCookie(&http.Cookie{Name: sessionCookie, Value: sessionID})
+	_, _, called, _ = run(req, "/clock")
+	assert.True(t, called, "the session cookie should be accepted")
+
+	auth.RevokeToken(token.ID)
+	_, _, called, _ = run(httptest.NewRequest(http.MethodGet, "/clock?token="+plain, nil), "/clock")
+	assert.False(t, called, "revoked tokens should not be accepted")
+
+	t.Run("Throttle", func(t *testing.T) {
+		attempt := func(password string) (bool, error) {
+			req := httptest.NewRequest(http.MethodGet, "/clock?password="+password, nil)
+			req.Header.Set(echo.HeaderXRealIP, "192.0.2.99")
+			_, _, called, err := run(req, "/clock")
+			return called, err
+		}
+
+		for range failedAuthBurst {
+			called, err := attempt("wrong")
+			assert.False(t, called)
+			assert.NoError(t, err)
+		}
+
+		called, err := attempt("1234")
+		assert.False(t, called, "IPs sending wrong passwords should be throttled")
+		assert.Equal(t, echo.ErrTooManyRequests, err)
+
+		req := httptest.NewRequest(http.MethodGet, "/clock?password=1234", nil)
+		req.Header.Set(echo.HeaderXRealIP, "192.0.2.100")
+		_, _, called, _ = run(req, "/clock")
+		assert.True(t, called, "other IPs should not be throttled")
+	})
+}
+
+// TestRequireAdmin tests the admin routes need the admin password, and are forbidden until it is set
+func TestRequireAdmin(t *testing.T) {
+	baseConfig := config.New()
+	baseConfig.Kiosk.Password = "1234"
+
+	e := echo.New()
+
+	run := func(req *http.Request) error {
+		c := e.NewContext(req, httptest.NewRecorder())
+		return RequireAdmin(baseConfig)(func(c echo.Context) error {
+			return c.NoContent(http.StatusOK)
+		})(c)
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "/cache/flush", nil)
+	req.SetBasicAuth("admin", "1234")
+	err := run(req)
+	var httpErr *echo.HTTPError
+	if assert.ErrorAs(t, err, &httpErr) {
+		assert.Equal(t, http.StatusForbidden, httpErr.Code, "the Kiosk password should not be an admin password")
+	}
+
+	baseConfig.Kiosk.AdminPassword = "admin-1234"
+	assert.Equal(t, auth.Credential{Secret: "admin-1234"}, adminCredential(baseConfig))
+
+	err = run(req)
+	if assert.ErrorAs(t, err, &httpErr) {
+		assert.Equal(t, http.StatusUnauthorized, httpErr.Code)
+	}
+
+	req.SetBasicAuth("admin", "admin-1234")
+	assert.NoError(t, run(req))
+
+	req.SetBasicAuth("kiosk", "admin-1234")
+	assert.Error(t, run(req), "the username should be checked")
+}
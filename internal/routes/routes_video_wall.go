@@ -0,0 +1,139 @@
+package routes
+
+import (
+	"image"
+	"math"
+
+	"github.com/disintegration/imaging"
+	"github.com/labstack/echo/v4"
+
+	"github.com/damongolding/immich-kiosk/internal/common"
+	"github.com/damongolding/immich-kiosk/internal/config"
+	"github.com/damongolding/immich-kiosk/internal/groups"
+	"github.com/damongolding/immich-kiosk/internal/immich"
+	"github.com/damongolding/immich-kiosk/internal/utils"
+)
+
+const (
+	// wallSlidePosition is the group slide position used for the image shared by the whole video wall
+	wallSlidePosition = -1
+
+	// wallDefaultWidth and wallDefaultHeight are used when a display has not sent its size
+	wallDefaultWidth  = 1920
+	wallDefaultHeight = 1080
+)
+
+// wallSlide is the image shown across a video wall, before it is cut into tiles
+type wallSlide struct {
+	img         image.Image
+	immichImage immich.ImmichAsset
+}
+
+// isVideoWall reports whether the device is a display in a video wall
+func isVideoWall(requestConfig config.Config) bool {
+	return isGroupMode(requestConfig) && requestConfig.WallColumns*requestConfig.WallRows > 1
+}
+
+// videoWallViewData returns the device's tile of the image its video wall shows in slot.
+// The image is picked once for the whole wall and each display is sent the part of it in front of it.
+func videoWallViewData(c echo.Context, requestConfig config.Config, deviceID string, slot groups.Slot) (common.ViewData, error) {
+	requestID := utils.ColorizeRequestId(c.Response().Header().Get(echo.HeaderXRequestID))
+	screenWidth, screenHeight := wallScreenSize(requestConfig)
+
+	slide, err := groups.Slide(requestConfig.Group, slot, wallSlidePosition, func() (wallSlide, error) {
+		immichImage := immich.NewImage(requestConfig)
+
+		wallWidth, wallHeight := wallSize(requestConfig, screenWidth, screenHeight)
+		if wallWidth >= wallHeight {
+			immichImage.RatioWanted = immich.LandscapeOrientation
+		} else {
+			immichImage.RatioWanted = immich.PortraitOrientation
+		}
+
+		img, err := processImage(&immichImage, requestConfig, requestID, deviceID, false)
+		if err != nil {
+			return wallSlide{}, err
+		}
+
+		img = applyImageFilters(img, requestConfig, requestID, deviceID, false)
+
+		return wallSlide{img: img, immichImage: immichImage}, nil
+	})
+	if err != nil {
+		return common.ViewData{}, err
+	}
+
+	tile := imaging.Crop(slide.img, wallTile(slide.img.Bounds(), requestConfig, screenWidth, screenHeight))
+
+	// send no more pixels than the display can show
+	dpr := max(1, requestConfig.ClientData.DevicePixelRatio)
+	if maxWidth := int(math.Round(float64(screenWidth) * dpr)); tile.Bounds().Dx() > maxWidth {
+		tile = imaging.Resize(tile, maxWidth, 0, imaging.Lanczos)
+	}
+
+	imgString, err := imageToBase64(tile, requestConfig.ImageFormat, requestConfig.ImageQuality, requestConfig, requestID, deviceID, "Converted", false)
+	if err != nil {
+		return common.ViewData{}, err
+	}
+
+	// tiles fill their display and line up with their neighbours, so effects, backgrounds and image details are not used
+	requestConfig.Layout = "single"
+	requestConfig.ImageFit = "cover"
+	requestConfig.ImageEffect = ""
+	requestConfig.BackgroundBlur = false
+	requestConfig.DisableUi = true
+	requestConfig.Frameless = true
+
+	return common.ViewData{
+		DeviceID: deviceID,
+		Config:   requestConfig,
+		Images: []common.ViewImageData{
+			{
+				ImmichImage: slide.immichImage,
+				ImageData:   imgString,
+			},
+		},
+	}, nil
+}
+
+// wallScreenSize returns the size of the device's display, assuming every display in the wall is the same size.
+func wallScreenSize(requestConfig config.Config) (int, int) {
+	if requestConfig.ClientData.Width > 0 && requestConfig.ClientData.Height > 0 {
+		return requestConfig.ClientData.Width, requestConfig.ClientData.Height
+	}
+
+	return wallDefaultWidth, wallDefaultHeight
+}
+
+// wallSize returns the size of the whole video wall, including the gaps left for bezels.
+func wallSize(requestConfig config.Config, screenWidth, screenHeight int) (int, int) {
+	width := requestConfig.WallColumns*screenWidth + (requestConfig.WallColumns-1)*requestConfig.WallBezel
+	height := requestConfig.WallRows*screenHeight + (requestConfig.WallRows-1)*requestConfig.WallBezel
+
+	return width, height
+}
+
+// wallTile returns the part of an image shown by the device's display.
+// The image is scaled to cover the whole wall, cropping the edges that do not fit, and the part of the
+// image behind each bezel is skipped so the picture lines up across displays.
+func wallTile(bounds image.Rectangle, requestConfig config.Config, screenWidth, screenHeight int) image.Rectangle {
+	wallWidth, wallHeight := wallSize(requestConfig, screenWidth, screenHeight)
+
+	// wall pixels per image pixel
+	scale := max(float64(wallWidth)/float64(bounds.Dx()), float64(wallHeight)/float64(bounds.Dy()))
+
+	offsetX := (float64(bounds.Dx())*scale - float64(wallWidth)) / 2
+	offsetY := (float64(bounds.Dy())*scale - float64(wallHeight)) / 2
+
+	x := offsetX + float64((requestConfig.WallColumn-1)*(screenWidth+requestConfig.WallBezel))
+	y := offsetY + float64((requestConfig.WallRow-1)*(screenHeight+requestConfig.WallBezel))
+
+	tile := image.Rect(
+		int(math.Round(x/scale)),
+		int(math.Round(y/scale)),
+		int(math.Round((x+float64(screenWidth))/scale)),
+		int(math.Round((y+float64(screenHeight))/scale)),
+	)
+
+	return tile.Add(bounds.Min).Intersect(bounds)
+}
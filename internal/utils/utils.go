@@ -22,6 +22,7 @@ import (
 	"net/http"
 	"net/url"
 	"os"
+	"path"
 	"regexp"
 	"strconv"
 	"strings"
@@ -51,8 +52,18 @@ const (
 	// SigmaConstant is used to normalise the blur effect across different image sizes.
 	// The value 1300.0 was chosen as it provides consistent blur effects for typical screen resolutions.
 	SigmaConstant = 1300.0
+
+	// ImageFormatJPEG and ImageFormatPNG are the formats images can be encoded in
+	ImageFormatJPEG = "jpeg"
+	ImageFormatPNG  = "png"
+
+	// DefaultJPEGQuality is the JPEG quality used when none (or an invalid one) is given
+	DefaultJPEGQuality = 95
 )
 
+// ImageFormats lists every format ImageToBytes can encode images in
+var ImageFormats = []string{ImageFormatJPEG, ImageFormatPNG}
+
 // WeightedAsset represents an asset with a type and ID
 type WeightedAsset struct {
 	Type kiosk.Source
@@ -71,6 +82,12 @@ func GenerateUUID() string {
 	return uuid.New().String()
 }
 
+// IsValidUUID reports whether s is a valid UUID, e.g. an Immich asset ID
+func IsValidUUID(s string) bool {
+	_, err := uuid.Parse(s)
+	return err == nil
+}
+
 // DateToLayout takes a string and replaces normal date layouts to GO layouts
 func DateToLayout(input string) string {
 	replacer := strings.NewReplacer(
@@ -101,14 +118,26 @@ func DateToJavascriptLayout(input string) string {
 	return replacer.Replace(input)
 }
 
-// ImageToBytes converts an image.Image to a byte slice in JPEG format.
+// ImageToBytes converts an image.Image to a byte slice in the given format (jpeg or png).
+// Quality (1-100) is only used for JPEG, out of range values use DefaultJPEGQuality.
 // It takes an image.Image as input and returns the encoded bytes and any error encountered.
 // The bytes can be used for further processing, transmission, or storage.
-func ImageToBytes(img image.Image) ([]byte, error) {
+func ImageToBytes(img image.Image, format string, quality int) ([]byte, error) {
 
 	buf := new(bytes.Buffer)
 
-	err := imaging.Encode(buf, img, imaging.JPEG)
+	var err error
+
+	switch format {
+	case ImageFormatPNG:
+		err = imaging.Encode(buf, img, imaging.PNG)
+	default:
+		if quality < 1 || quality > 100 {
+			quality = DefaultJPEGQuality
+		}
+		err = imaging.Encode(buf, img, imaging.JPEG, imaging.JPEGQuality(quality))
+	}
+
 	if err != nil {
 		return buf.Bytes(), err
 	}
@@ -116,15 +145,34 @@ func ImageToBytes(img image.Image) ([]byte, error) {
 	return buf.Bytes(), nil
 }
 
+// ImageFormatMimeType returns the MIME type for an image format used by ImageToBytes.
+func ImageFormatMimeType(format string) string {
+	if format == ImageFormatPNG {
+		return "image/png"
+	}
+
+	return "image/jpeg"
+}
+
 // BytesToImage converts a byte slice to an image.Image.
 // It takes a byte slice as input and returns an image.Image and any error encountered.
 // It handles both WebP and other common image formats (JPEG, PNG, GIF) automatically
 // by detecting the MIME type and using the appropriate decoder.
+// The image header is checked first and ErrImageTooLarge is returned, without decoding,
+// for images over the pixel budget. Only a limited number of images are decoded at the same time
+// (see SetDecodeLimits).
 func BytesToImage(imgBytes []byte) (image.Image, error) {
 
 	var img image.Image
 	var err error
 
+	if err = checkDecodeBudget(imgBytes); err != nil {
+		return nil, err
+	}
+
+	release := AcquireDecodeSlot()
+	defer release()
+
 	imageMime := ImageMimeType(bytes.NewReader(imgBytes))
 
 	switch imageMime {
@@ -199,23 +247,22 @@ func ApplyExifOrientation(img image.Image, isLandscape bool, exifOrientation str
 	}
 }
 
-// ImageToBase64 converts an image.Image to a base64 encoded data URI string with appropriate MIME type
-func ImageToBase64(img image.Image) (string, error) {
-
-	var buf bytes.Buffer
+// ImageToBase64 converts an image.Image to a base64 encoded data URI string with appropriate MIME type.
+// The image is encoded in the given format and quality, see ImageToBytes.
+func ImageToBase64(img image.Image, format string, quality int) (string, error) {
 
-	err := imaging.Encode(&buf, img, imaging.JPEG)
+	imgBytes, err := ImageToBytes(img, format, quality)
 	if err != nil {
 		return "", err
 	}
 
 	var base64Encoding string
 
-	mimeType := http.DetectContentType(buf.Bytes())
+	mimeType := http.DetectContentType(imgBytes)
 
 	base64Encoding += fmt.Sprintf("data:%s;base64,", mimeType)
 
-	base64Encoding += base64.StdEncoding.EncodeToString(buf.Bytes())
+	base64Encoding += base64.StdEncoding.EncodeToString(imgBytes)
 
 	return base64Encoding, nil
 }
@@ -622,6 +669,29 @@ func CreateQrCode(link string) string {
 	return i
 }
 
+// ImmichAssetURL constructs the URL for viewing an asset in Immich.
+// The external URL is used if set, otherwise the base URL.
+// Returns '#' if the URL cannot be parsed.
+func ImmichAssetURL(baseUrl, externalUrl, assetID string) string {
+
+	urlToParse := externalUrl
+	if urlToParse == "" {
+		urlToParse = baseUrl
+	}
+
+	u, err := url.Parse(urlToParse)
+	if err != nil {
+		log.Warn("Failed to parse base URL", "error", err)
+		return "#"
+	}
+
+	return (&url.URL{
+		Scheme: u.Scheme,
+		Host:   u.Host,
+		Path:   path.Join("photos", assetID),
+	}).String()
+}
+
 // GenerateSharedSecret generates a random 256-bit (32-byte) secret and returns it as a hex string.
 func GenerateSharedSecret() (string, error) {
 	secret := make([]byte, 32)
@@ -0,0 +1,130 @@
+package utils
+
+import (
+	"fmt"
+	"image"
+
+	"github.com/disintegration/imaging"
+)
+
+const (
+	// colorSampleSize is the size images are shrunk to before sampling colours.
+	// Sampling a thumbnail keeps colour extraction cheap on low powered devices.
+	colorSampleSize = 64
+	// edgeSampleFraction is the fraction of the image width/height sampled for edge colours
+	edgeSampleFraction = 0.1
+)
+
+// NewColor returns a Color with its RGB and Hex string representations filled in.
+func NewColor(r, g, b int) Color {
+	return Color{
+		R:   r,
+		G:   g,
+		B:   b,
+		RGB: fmt.Sprintf("rgb(%d, %d, %d)", r, g, b),
+		Hex: fmt.Sprintf("#%02X%02X%02X", r, g, b),
+	}
+}
+
+// MixColors blends two colours together. Amount ranges from 0 (all a) to 1 (all b).
+func MixColors(a, b Color, amount float64) Color {
+	mix := func(x, y int) int {
+		return int(clampUint8(float64(x) + (float64(y)-float64(x))*amount))
+	}
+	return NewColor(mix(a.R, b.R), mix(a.G, b.G), mix(a.B, b.B))
+}
+
+// sampleImage shrinks an image so colours can be sampled cheaply.
+func sampleImage(img image.Image) *image.NRGBA {
+	return imaging.Fit(img, colorSampleSize, colorSampleSize, imaging.Box)
+}
+
+// averageColor returns the average colour of the pixels inside rect.
+func averageColor(img *image.NRGBA, rect image.Rectangle) Color {
+	rect = rect.Intersect(img.Bounds())
+
+	var r, g, b, count int
+	for y := rect.Min.Y; y < rect.Max.Y; y++ {
+		for x := rect.Min.X; x < rect.Max.X; x++ {
+			i := img.PixOffset(x, y)
+			r += int(img.Pix[i+0])
+			g += int(img.Pix[i+1])
+			b += int(img.Pix[i+2])
+			count++
+		}
+	}
+
+	if count == 0 {
+		return NewColor(0, 0, 0)
+	}
+
+	return NewColor(r/count, g/count, b/count)
+}
+
+// DominantColor returns the most common colour in an image.
+// Pixels are grouped into coarse colour buckets and the average colour of the
+// most populated bucket is returned, so near identical shades count together.
+func DominantColor(img image.Image) Color {
+	sample := sampleImage(img)
+
+	type bucket struct {
+		r, g, b, count int
+	}
+
+	buckets := make(map[int]*bucket)
+	var dominant *bucket
+
+	for i := 0; i < len(sample.Pix); i += 4 {
+		r, g, b := int(sample.Pix[i]), int(sample.Pix[i+1]), int(sample.Pix[i+2])
+		key := (r>>4)<<8 | (g>>4)<<4 | (b >> 4)
+
+		bk, ok := buckets[key]
+		if !ok {
+			bk = &bucket{}
+			buckets[key] = bk
+		}
+
+		bk.r += r
+		bk.g += g
+		bk.b += b
+		bk.count++
+
+		if dominant == nil || bk.count > dominant.count {
+			dominant = bk
+		}
+	}
+
+	if dominant == nil {
+		return NewColor(0, 0, 0)
+	}
+
+	return NewColor(dominant.r/dominant.count, dominant.g/dominant.count, dominant.b/dominant.count)
+}
+
+// EdgeColors returns the average colours along the top, bottom, left and right edges of an image.
+func EdgeColors(img image.Image) (top, bottom, left, right Color) {
+	sample := sampleImage(img)
+	bounds := sample.Bounds()
+
+	edgeWidth := max(1, int(float64(bounds.Dx())*edgeSampleFraction))
+	edgeHeight := max(1, int(float64(bounds.Dy())*edgeSampleFraction))
+
+	top = averageColor(sample, image.Rect(bounds.Min.X, bounds.Min.Y, bounds.Max.X, bounds.Min.Y+edgeHeight))
+	bottom = averageColor(sample, image.Rect(bounds.Min.X, bounds.Max.Y-edgeHeight, bounds.Max.X, bounds.Max.Y))
+	left = averageColor(sample, image.Rect(bounds.Min.X, bounds.Min.Y, bounds.Min.X+edgeWidth, bounds.Max.Y))
+	right = averageColor(sample, image.Rect(bounds.Max.X-edgeWidth, bounds.Min.Y, bounds.Max.X, bounds.Max.Y))
+
+	return top, bottom, left, right
+}
+
+// MatteColor returns a passe-partout colour for an image. It is a pale tint of the
+// dominant colour, or a deep shade of it for dark images, so the matte complements the photo.
+func MatteColor(img image.Image) Color {
+	dominant := DominantColor(img)
+
+	if calculateLuminance(dominant) < 0.05 {
+		return MixColors(dominant, NewColor(0, 0, 0), 0.6)
+	}
+
+	return MixColors(dominant, NewColor(245, 242, 235), 0.8)
+}
@@ -0,0 +1,82 @@
+package utils
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"sync/atomic"
+)
+
+const (
+	// DefaultMaxImageMegapixels is the largest image (in megapixels) decoded by default.
+	// A decoded 50MP image needs roughly 200MB of memory.
+	DefaultMaxImageMegapixels = 50
+	// DefaultMaxConcurrentDecodes is how many images are decoded at the same time by default.
+	DefaultMaxConcurrentDecodes = 2
+)
+
+// ErrImageTooLarge is returned when an image is larger than the decode pixel budget.
+var ErrImageTooLarge = errors.New("image exceeds decode pixel budget")
+
+var (
+	// maxDecodePixels is the pixel budget for a single decoded image
+	maxDecodePixels atomic.Int64
+	// decodeSemaphore limits how many images are decoded at the same time
+	decodeSemaphore = make(chan struct{}, DefaultMaxConcurrentDecodes)
+)
+
+func init() {
+	maxDecodePixels.Store(DefaultMaxImageMegapixels * 1_000_000)
+}
+
+// SetDecodeLimits sets the pixel budget for a single image and how many images can be decoded at the same time.
+// Values below 1 use the defaults. It should be called at start up, before any images are decoded.
+func SetDecodeLimits(maxMegapixels float64, maxConcurrent int) {
+	if maxMegapixels <= 0 {
+		maxMegapixels = DefaultMaxImageMegapixels
+	}
+
+	if maxConcurrent < 1 {
+		maxConcurrent = DefaultMaxConcurrentDecodes
+	}
+
+	maxDecodePixels.Store(int64(maxMegapixels * 1_000_000))
+	decodeSemaphore = make(chan struct{}, maxConcurrent)
+}
+
+// checkDecodeBudget reads the image header and returns ErrImageTooLarge if
+// decoding the image would exceed the pixel budget.
+func checkDecodeBudget(imgBytes []byte) error {
+	cfg, format, err := image.DecodeConfig(bytes.NewReader(imgBytes))
+	if err != nil {
+		// unknown headers are left for the decoder to report
+		return nil
+	}
+
+	if err := CheckPixelBudget(cfg.Width, cfg.Height); err != nil {
+		return fmt.Errorf("%s %w", format, err)
+	}
+
+	return nil
+}
+
+// CheckPixelBudget returns ErrImageTooLarge if an image of the given size would exceed the pixel budget.
+// It is used for images Kiosk creates, e.g. rendered frames, as well as ones it decodes.
+func CheckPixelBudget(width, height int) error {
+	pixels := int64(width) * int64(height)
+	if budget := maxDecodePixels.Load(); pixels > budget {
+		return fmt.Errorf("%w: %dx%d (%.1fMP) is larger than %.1fMP", ErrImageTooLarge, width, height, float64(pixels)/1_000_000, float64(budget)/1_000_000)
+	}
+
+	return nil
+}
+
+// AcquireDecodeSlot blocks until an image can be decoded and returns a function that releases the slot.
+// Rendering a full size image takes a slot too, as it needs as much memory as decoding one.
+// Slots are not re-entrant, so callers must not decode while holding one.
+func AcquireDecodeSlot() func() {
+	semaphore := decodeSemaphore
+	semaphore <- struct{}{}
+	return func() { <-semaphore }
+}
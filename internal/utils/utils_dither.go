@@ -0,0 +1,223 @@
+package utils
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"math"
+)
+
+// DiffusionKernel describes how the quantisation error of a pixel is spread to its neighbours.
+type DiffusionKernel struct {
+	// Divisor is the value each weight is divided by
+	Divisor float64
+	// Weights holds the neighbouring pixel offsets and their share of the error
+	Weights []DiffusionWeight
+}
+
+// DiffusionWeight is the share of the quantisation error given to the pixel at DX,DY.
+type DiffusionWeight struct {
+	DX     int
+	DY     int
+	Weight float64
+}
+
+var (
+	// FloydSteinberg spreads the full error to four neighbouring pixels.
+	FloydSteinberg = DiffusionKernel{
+		Divisor: 16,
+		Weights: []DiffusionWeight{
+			{DX: 1, DY: 0, Weight: 7},
+			{DX: -1, DY: 1, Weight: 3},
+			{DX: 0, DY: 1, Weight: 5},
+			{DX: 1, DY: 1, Weight: 1},
+		},
+	}
+
+	// Atkinson spreads three quarters of the error to six neighbouring pixels,
+	// giving higher contrast results that suit low resolution panels.
+	Atkinson = DiffusionKernel{
+		Divisor: 8,
+		Weights: []DiffusionWeight{
+			{DX: 1, DY: 0, Weight: 1},
+			{DX: 2, DY: 0, Weight: 1},
+			{DX: -1, DY: 1, Weight: 1},
+			{DX: 0, DY: 1, Weight: 1},
+			{DX: 1, DY: 1, Weight: 1},
+			{DX: 0, DY: 2, Weight: 1},
+		},
+	}
+
+	// NoDiffusion maps each pixel to its nearest palette colour without spreading the error.
+	NoDiffusion = DiffusionKernel{Divisor: 1}
+
+	// PaletteMono is a black and white palette for monochrome e-paper panels.
+	PaletteMono = color.Palette{
+		color.RGBA{R: 0, G: 0, B: 0, A: 255},
+		color.RGBA{R: 255, G: 255, B: 255, A: 255},
+	}
+
+	// PaletteGrey4 is a four level greyscale palette.
+	PaletteGrey4 = color.Palette{
+		color.RGBA{R: 0, G: 0, B: 0, A: 255},
+		color.RGBA{R: 85, G: 85, B: 85, A: 255},
+		color.RGBA{R: 170, G: 170, B: 170, A: 255},
+		color.RGBA{R: 255, G: 255, B: 255, A: 255},
+	}
+
+	// PaletteACeP7 is the seven colour palette used by ACeP (Advanced Color ePaper) panels.
+	// The order matches the colour indices expected by the panels.
+	PaletteACeP7 = color.Palette{
+		color.RGBA{R: 0, G: 0, B: 0, A: 255},       // black
+		color.RGBA{R: 255, G: 255, B: 255, A: 255}, // white
+		color.RGBA{R: 0, G: 255, B: 0, A: 255},     // green
+		color.RGBA{R: 0, G: 0, B: 255, A: 255},     // blue
+		color.RGBA{R: 255, G: 0, B: 0, A: 255},     // red
+		color.RGBA{R: 255, G: 255, B: 0, A: 255},   // yellow
+		color.RGBA{R: 255, G: 128, B: 0, A: 255},   // orange
+	}
+)
+
+// DitherImage reduces an image to the given palette using error diffusion with the given kernel.
+// Error is accumulated in float buffers so it is not clipped between pixels.
+// Only the rows the kernel reaches are buffered, so memory use grows with the width, not the whole image.
+func DitherImage(img image.Image, palette color.Palette, kernel DiffusionKernel) *image.Paletted {
+	bounds := img.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+
+	dithered := image.NewPaletted(image.Rect(0, 0, width, height), palette)
+
+	if width == 0 || height == 0 || len(palette) == 0 {
+		return dithered
+	}
+
+	// the current row plus every row below it the kernel spreads error to
+	rows := 1
+	for _, w := range kernel.Weights {
+		rows = max(rows, w.DY+1)
+	}
+
+	// working copy of the buffered rows as r,g,b floats, row y is kept at (y % rows)
+	buf := make([][3]float64, width*rows)
+	loadRow := func(y int) {
+		row := buf[(y%rows)*width : (y%rows+1)*width]
+		for x := 0; x < width; x++ {
+			r, g, b, _ := img.At(bounds.Min.X+x, bounds.Min.Y+y).RGBA()
+			row[x] = [3]float64{float64(r >> 8), float64(g >> 8), float64(b >> 8)}
+		}
+	}
+
+	for y := 0; y < min(rows, height); y++ {
+		loadRow(y)
+	}
+
+	paletteRGB := make([][3]float64, len(palette))
+	for i, c := range palette {
+		r, g, b, _ := c.RGBA()
+		paletteRGB[i] = [3]float64{float64(r >> 8), float64(g >> 8), float64(b >> 8)}
+	}
+
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			current := buf[(y%rows)*width+x]
+
+			index := nearestPaletteIndex(current, paletteRGB)
+			dithered.SetColorIndex(x, y, uint8(index))
+
+			if len(kernel.Weights) == 0 {
+				continue
+			}
+
+			picked := paletteRGB[index]
+			quantError := [3]float64{
+				current[0] - picked[0],
+				current[1] - picked[1],
+				current[2] - picked[2],
+			}
+
+			for _, w := range kernel.Weights {
+				nx, ny := x+w.DX, y+w.DY
+				if nx < 0 || nx >= width || ny >= height {
+					continue
+				}
+
+				share := w.Weight / kernel.Divisor
+				neighbour := &buf[(ny%rows)*width+nx]
+				neighbour[0] += quantError[0] * share
+				neighbour[1] += quantError[1] * share
+				neighbour[2] += quantError[2] * share
+			}
+		}
+
+		// the finished row's slot is reused for the next row the kernel will reach
+		if next := y + rows; next < height {
+			loadRow(next)
+		}
+	}
+
+	return dithered
+}
+
+// nearestPaletteIndex returns the index of the palette colour closest to c,
+// using a luma weighted euclidean distance.
+func nearestPaletteIndex(c [3]float64, palette [][3]float64) int {
+	bestIndex := 0
+	bestDistance := math.MaxFloat64
+
+	for i, p := range palette {
+		dr := c[0] - p[0]
+		dg := c[1] - p[1]
+		db := c[2] - p[2]
+
+		distance := 0.299*dr*dr + 0.587*dg*dg + 0.114*db*db
+		if distance < bestDistance {
+			bestDistance = distance
+			bestIndex = i
+		}
+	}
+
+	return bestIndex
+}
+
+// BitsPerPixel returns the number of bits needed to store an index into a palette of the given size.
+func BitsPerPixel(paletteSize int) int {
+	switch {
+	case paletteSize <= 2:
+		return 1
+	case paletteSize <= 4:
+		return 2
+	case paletteSize <= 16:
+		return 4
+	default:
+		return 8
+	}
+}
+
+// PackPalettedImage packs the palette indices of an image into a raw bitmap.
+// Pixels are stored row by row, most significant bits first, with each row padded to a whole byte.
+// This is the format expected by most e-paper panel drivers.
+func PackPalettedImage(img *image.Paletted) ([]byte, error) {
+	bitsPerPixel := BitsPerPixel(len(img.Palette))
+
+	bounds := img.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+
+	pixelsPerByte := 8 / bitsPerPixel
+	rowBytes := (width + pixelsPerByte - 1) / pixelsPerByte
+
+	packed := make([]byte, rowBytes*height)
+
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			index := img.ColorIndexAt(bounds.Min.X+x, bounds.Min.Y+y)
+			if int(index) >= len(img.Palette) {
+				return nil, fmt.Errorf("pixel %d,%d has index %d outside of palette", x, y, index)
+			}
+
+			shift := uint(8 - bitsPerPixel*(x%pixelsPerByte+1))
+			packed[y*rowBytes+x/pixelsPerByte] |= index << shift
+		}
+	}
+
+	return packed, nil
+}
@@ -0,0 +1,190 @@
+package utils
+
+import (
+	"image"
+	"image/color"
+	"math"
+
+	"github.com/disintegration/imaging"
+)
+
+const (
+	// autoContrastClip is the fraction of the darkest and brightest pixels ignored when stretching contrast,
+	// so a few specular highlights or deep shadows do not stop the image from being stretched.
+	autoContrastClip = 0.01
+
+	// privacyBlocks is the number of blocks across the longest side a blurred region is pixelated to
+	privacyBlocks = 8
+	// privacyBlurDivisor sets the blur sigma of a blurred region relative to its size
+	privacyBlurDivisor = 10.0
+)
+
+// SepiaImage applies a classic sepia tone to an image.
+func SepiaImage(img image.Image) *image.NRGBA {
+	return imaging.AdjustFunc(img, func(c color.NRGBA) color.NRGBA {
+		r, g, b := float64(c.R), float64(c.G), float64(c.B)
+
+		return color.NRGBA{
+			R: clampUint8(0.393*r + 0.769*g + 0.189*b),
+			G: clampUint8(0.349*r + 0.686*g + 0.168*b),
+			B: clampUint8(0.272*r + 0.534*g + 0.131*b),
+			A: c.A,
+		}
+	})
+}
+
+// WarmImage applies a warm, slightly dimmed tint that is easier on the eyes at night.
+func WarmImage(img image.Image) *image.NRGBA {
+	return imaging.AdjustFunc(img, func(c color.NRGBA) color.NRGBA {
+		return color.NRGBA{
+			R: clampUint8(float64(c.R) * 0.95),
+			G: clampUint8(float64(c.G) * 0.82),
+			B: clampUint8(float64(c.B) * 0.6),
+			A: c.A,
+		}
+	})
+}
+
+// VignetteImage darkens the edges of an image. Strength ranges from 0 (no effect) to 1 (black corners).
+func VignetteImage(img image.Image, strength float64) *image.NRGBA {
+	src := imaging.Clone(img)
+
+	width, height := src.Bounds().Dx(), src.Bounds().Dy()
+	if width == 0 || height == 0 {
+		return src
+	}
+
+	strength = math.Max(0, math.Min(1, strength))
+
+	centerX, centerY := float64(width)/2, float64(height)/2
+	maxDistance := math.Hypot(centerX, centerY)
+
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			distance := math.Hypot(float64(x)+0.5-centerX, float64(y)+0.5-centerY) / maxDistance
+			factor := 1 - strength*distance*distance
+
+			i := src.PixOffset(x, y)
+			src.Pix[i+0] = clampUint8(float64(src.Pix[i+0]) * factor)
+			src.Pix[i+1] = clampUint8(float64(src.Pix[i+1]) * factor)
+			src.Pix[i+2] = clampUint8(float64(src.Pix[i+2]) * factor)
+		}
+	}
+
+	return src
+}
+
+// VintageImage gives an image a faded, warm film look with darkened edges.
+func VintageImage(img image.Image) *image.NRGBA {
+	vintage := imaging.AdjustSaturation(img, -35)
+	vintage = imaging.AdjustContrast(vintage, -15)
+	vintage = imaging.AdjustFunc(vintage, func(c color.NRGBA) color.NRGBA {
+		// lift the blacks and add a yellow cast
+		return color.NRGBA{
+			R: clampUint8(float64(c.R)*0.9 + 30),
+			G: clampUint8(float64(c.G)*0.88 + 22),
+			B: clampUint8(float64(c.B)*0.8 + 10),
+			A: c.A,
+		}
+	})
+
+	return VignetteImage(vintage, 0.45)
+}
+
+// AutoContrastImage stretches the luminance range of an image so its darkest
+// and brightest pixels (ignoring the outer 1%) become black and white.
+func AutoContrastImage(img image.Image) *image.NRGBA {
+	src := imaging.Clone(img)
+
+	var histogram [256]int
+	total := 0
+
+	for i := 0; i < len(src.Pix); i += 4 {
+		histogram[luminance(src.Pix[i], src.Pix[i+1], src.Pix[i+2])]++
+		total++
+	}
+
+	if total == 0 {
+		return src
+	}
+
+	clip := int(float64(total) * autoContrastClip)
+
+	low, count := 0, 0
+	for ; low < 255; low++ {
+		count += histogram[low]
+		if count > clip {
+			break
+		}
+	}
+
+	high, count := 255, 0
+	for ; high > 0; high-- {
+		count += histogram[high]
+		if count > clip {
+			break
+		}
+	}
+
+	if high <= low {
+		return src
+	}
+
+	scale := 255 / float64(high-low)
+
+	for i := 0; i < len(src.Pix); i += 4 {
+		src.Pix[i+0] = clampUint8((float64(src.Pix[i+0]) - float64(low)) * scale)
+		src.Pix[i+1] = clampUint8((float64(src.Pix[i+1]) - float64(low)) * scale)
+		src.Pix[i+2] = clampUint8((float64(src.Pix[i+2]) - float64(low)) * scale)
+	}
+
+	return src
+}
+
+// luminance returns the Rec. 601 luma of an RGB colour.
+func luminance(r, g, b uint8) uint8 {
+	return clampUint8(0.299*float64(r) + 0.587*float64(g) + 0.114*float64(b))
+}
+
+// clampUint8 rounds and clamps a float to the 0-255 range.
+func clampUint8(v float64) uint8 {
+	switch {
+	case v <= 0:
+		return 0
+	case v >= 255:
+		return 255
+	default:
+		return uint8(v + 0.5)
+	}
+}
+
+// BlurRegions heavily blurs the given regions of an image, e.g. to hide faces.
+// Each region is pixelated before it is blurred so detail cannot be recovered by sharpening.
+// Regions are clipped to the image bounds.
+func BlurRegions(img image.Image, regions []image.Rectangle) image.Image {
+	if len(regions) == 0 {
+		return img
+	}
+
+	dst := imaging.Clone(img)
+	offset := img.Bounds().Min
+
+	for _, region := range regions {
+		region = region.Sub(offset).Intersect(dst.Bounds())
+		if region.Empty() {
+			continue
+		}
+
+		size := max(region.Dx(), region.Dy())
+		blockSize := max(1, size/privacyBlocks)
+
+		patch := imaging.Crop(dst, region)
+		patch = imaging.Resize(patch, max(1, region.Dx()/blockSize), max(1, region.Dy()/blockSize), imaging.Box)
+		patch = imaging.Resize(patch, region.Dx(), region.Dy(), imaging.NearestNeighbor)
+		patch = imaging.Blur(patch, float64(size)/privacyBlurDivisor)
+
+		dst = imaging.Paste(dst, patch, region.Min)
+	}
+
+	return dst
+}
@@ -0,0 +1,89 @@
+package utils
+
+import (
+	"image"
+
+	"github.com/disintegration/imaging"
+)
+
+const (
+	// qualitySampleSize is the size images are shrunk to before analysing their quality.
+	// Analysing a fixed size keeps scores comparable between image resolutions.
+	qualitySampleSize = 512
+	// clippedShadow and clippedHighlight are the luminance levels counted as clipped
+	clippedShadow    = 8
+	clippedHighlight = 247
+)
+
+// ImageQuality holds the quality scores of an image
+type ImageQuality struct {
+	// Sharpness is the variance of the Laplacian, low values indicate a blurry image
+	Sharpness float64
+	// Brightness is the mean luminance as a percentage (0-100)
+	Brightness float64
+	// ShadowClipping is the percentage of pixels that are (almost) black
+	ShadowClipping float64
+	// HighlightClipping is the percentage of pixels that are (almost) white
+	HighlightClipping float64
+}
+
+// AnalyzeImageQuality computes sharpness and exposure scores for an image.
+// The image is shrunk and converted to greyscale first so analysis is cheap.
+func AnalyzeImageQuality(img image.Image) ImageQuality {
+	sample := imaging.Grayscale(imaging.Fit(img, qualitySampleSize, qualitySampleSize, imaging.Box))
+
+	bounds := sample.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+	total := width * height
+
+	if total == 0 {
+		return ImageQuality{}
+	}
+
+	// greyscale images have equal r, g and b so the red channel is the luminance
+	lum := func(x, y int) float64 {
+		return float64(sample.Pix[sample.PixOffset(x, y)])
+	}
+
+	var quality ImageQuality
+	var sum float64
+	var shadows, highlights int
+
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			l := lum(x, y)
+			sum += l
+
+			switch {
+			case l <= clippedShadow:
+				shadows++
+			case l >= clippedHighlight:
+				highlights++
+			}
+		}
+	}
+
+	quality.Brightness = sum / float64(total) / 255 * 100
+	quality.ShadowClipping = float64(shadows) / float64(total) * 100
+	quality.HighlightClipping = float64(highlights) / float64(total) * 100
+
+	// variance of the 4-neighbour Laplacian over the inner pixels
+	if width > 2 && height > 2 {
+		var lapSum, lapSumSq float64
+		count := 0
+
+		for y := 1; y < height-1; y++ {
+			for x := 1; x < width-1; x++ {
+				lap := lum(x-1, y) + lum(x+1, y) + lum(x, y-1) + lum(x, y+1) - 4*lum(x, y)
+				lapSum += lap
+				lapSumSq += lap * lap
+				count++
+			}
+		}
+
+		mean := lapSum / float64(count)
+		quality.Sharpness = lapSumSq/float64(count) - mean*mean
+	}
+
+	return quality
+}
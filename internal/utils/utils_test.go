@@ -1,12 +1,19 @@
 package utils
 
 import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
 	"math"
+	"net/http"
 	"net/url"
 	"reflect"
 	"testing"
 	"time"
 
+	"github.com/disintegration/imaging"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -449,3 +456,275 @@ func TestMergeQueries(t *testing.T) {
 		})
 	}
 }
+
+// TestDitherImage checks solid colours map to their palette entry and mid grey is dithered
+func TestDitherImage(t *testing.T) {
+	white := image.NewRGBA(image.Rect(0, 0, 4, 4))
+	draw.Draw(white, white.Bounds(), image.NewUniform(color.White), image.Point{}, draw.Src)
+
+	dithered := DitherImage(white, PaletteMono, FloydSteinberg)
+	for _, index := range dithered.Pix {
+		assert.Equal(t, uint8(1), index, "White pixels should map to the white palette entry")
+	}
+
+	grey := image.NewRGBA(image.Rect(0, 0, 16, 16))
+	draw.Draw(grey, grey.Bounds(), image.NewUniform(color.RGBA{R: 128, G: 128, B: 128, A: 255}), image.Point{}, draw.Src)
+
+	for _, kernel := range []DiffusionKernel{FloydSteinberg, Atkinson} {
+		dithered = DitherImage(grey, PaletteMono, kernel)
+
+		whitePixels := 0
+		for _, index := range dithered.Pix {
+			whitePixels += int(index)
+		}
+
+		assert.Greater(t, whitePixels, 0, "Mid grey should contain white pixels")
+		assert.Less(t, whitePixels, len(dithered.Pix), "Mid grey should contain black pixels")
+	}
+
+	dithered = DitherImage(grey, PaletteMono, NoDiffusion)
+	assert.Equal(t, dithered.Pix[0], dithered.Pix[len(dithered.Pix)-1], "No diffusion should produce a flat image")
+}
+
+func TestPackPalettedImage(t *testing.T) {
+	tests := []struct {
+		name     string
+		palette  color.Palette
+		width    int
+		indices  []uint8
+		expected []byte
+	}{
+		{
+			name:     "Mono",
+			palette:  PaletteMono,
+			width:    10,
+			indices:  []uint8{1, 0, 1, 0, 1, 0, 1, 0, 1, 1},
+			expected: []byte{0b10101010, 0b11000000},
+		},
+		{
+			name:     "Grey4",
+			palette:  PaletteGrey4,
+			width:    4,
+			indices:  []uint8{3, 2, 1, 0},
+			expected: []byte{0b11100100},
+		},
+		{
+			name:     "ACeP7",
+			palette:  PaletteACeP7,
+			width:    3,
+			indices:  []uint8{6, 1, 4},
+			expected: []byte{0x61, 0x40},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			img := image.NewPaletted(image.Rect(0, 0, tt.width, 1), tt.palette)
+			copy(img.Pix, tt.indices)
+
+			packed, err := PackPalettedImage(img)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, packed)
+		})
+	}
+}
+
+func TestImageFilters(t *testing.T) {
+	grey := image.NewRGBA(image.Rect(0, 0, 20, 20))
+	draw.Draw(grey, grey.Bounds(), image.NewUniform(color.RGBA{R: 128, G: 128, B: 128, A: 255}), image.Point{}, draw.Src)
+
+	t.Run("Sepia", func(t *testing.T) {
+		c := SepiaImage(grey).NRGBAAt(10, 10)
+		assert.Greater(t, c.R, c.G, "Sepia should be warmer in red than green")
+		assert.Greater(t, c.G, c.B, "Sepia should be warmer in green than blue")
+	})
+
+	t.Run("Warm", func(t *testing.T) {
+		c := WarmImage(grey).NRGBAAt(10, 10)
+		assert.Greater(t, c.R, c.B, "Warm tint should reduce blue more than red")
+	})
+
+	t.Run("Vignette", func(t *testing.T) {
+		vignette := VignetteImage(grey, 0.8)
+		assert.Less(t, vignette.NRGBAAt(0, 0).R, vignette.NRGBAAt(10, 10).R, "Corners should be darker than the centre")
+	})
+
+	t.Run("AutoContrast", func(t *testing.T) {
+		lowContrast := image.NewRGBA(image.Rect(0, 0, 20, 20))
+		draw.Draw(lowContrast, image.Rect(0, 0, 10, 20), image.NewUniform(color.RGBA{R: 100, G: 100, B: 100, A: 255}), image.Point{}, draw.Src)
+		draw.Draw(lowContrast, image.Rect(10, 0, 20, 20), image.NewUniform(color.RGBA{R: 150, G: 150, B: 150, A: 255}), image.Point{}, draw.Src)
+
+		stretched := AutoContrastImage(lowContrast)
+		assert.Equal(t, uint8(0), stretched.NRGBAAt(0, 0).R, "Darkest pixels should become black")
+		assert.Equal(t, uint8(255), stretched.NRGBAAt(19, 19).R, "Brightest pixels should become white")
+	})
+}
+
+func TestBackgroundColors(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 100, 100))
+	draw.Draw(img, img.Bounds(), image.NewUniform(color.RGBA{R: 200, G: 30, B: 30, A: 255}), image.Point{}, draw.Src)
+	// a smaller blue region should not be picked as dominant
+	draw.Draw(img, image.Rect(0, 0, 100, 20), image.NewUniform(color.RGBA{R: 20, G: 40, B: 220, A: 255}), image.Point{}, draw.Src)
+
+	t.Run("DominantColor", func(t *testing.T) {
+		c := DominantColor(img)
+		assert.Equal(t, NewColor(200, 30, 30), c)
+		assert.Equal(t, "rgb(200, 30, 30)", c.RGB)
+		assert.Equal(t, "#C81E1E", c.Hex)
+	})
+
+	t.Run("EdgeColors", func(t *testing.T) {
+		top, bottom, left, right := EdgeColors(img)
+		assert.Equal(t, NewColor(20, 40, 220), top, "Top edge should be blue")
+		assert.Equal(t, NewColor(200, 30, 30), bottom, "Bottom edge should be red")
+		assert.Equal(t, left, right, "Left and right edges should match")
+	})
+
+	t.Run("MatteColor", func(t *testing.T) {
+		c := MatteColor(img)
+		assert.Greater(t, c.R, 200, "Matte should be a pale tint")
+		assert.Greater(t, c.R, c.B, "Matte should keep the dominant hue")
+	})
+
+	t.Run("MixColors", func(t *testing.T) {
+		black, white := NewColor(0, 0, 0), NewColor(255, 255, 255)
+		assert.Equal(t, black, MixColors(black, white, 0))
+		assert.Equal(t, white, MixColors(black, white, 1))
+		assert.Equal(t, NewColor(128, 128, 128), MixColors(black, white, 0.5))
+	})
+}
+
+func TestAnalyzeImageQuality(t *testing.T) {
+	checkerboard := image.NewRGBA(image.Rect(0, 0, 64, 64))
+	for y := 0; y < 64; y++ {
+		for x := 0; x < 64; x++ {
+			c := color.RGBA{R: 30, G: 30, B: 30, A: 255}
+			if (x/4+y/4)%2 == 0 {
+				c = color.RGBA{R: 220, G: 220, B: 220, A: 255}
+			}
+			checkerboard.Set(x, y, c)
+		}
+	}
+
+	sharp := AnalyzeImageQuality(checkerboard)
+	blurry := AnalyzeImageQuality(imaging.Blur(checkerboard, 6))
+
+	assert.Greater(t, sharp.Sharpness, blurry.Sharpness, "Blurring should lower the sharpness score")
+	assert.InDelta(t, 49, sharp.Brightness, 1)
+	assert.Zero(t, sharp.ShadowClipping)
+	assert.Zero(t, sharp.HighlightClipping)
+
+	black := image.NewRGBA(image.Rect(0, 0, 32, 32))
+	draw.Draw(black, black.Bounds(), image.NewUniform(color.Black), image.Point{}, draw.Src)
+
+	dark := AnalyzeImageQuality(black)
+	assert.Zero(t, dark.Brightness)
+	assert.Equal(t, 100.0, dark.ShadowClipping)
+	assert.Zero(t, dark.Sharpness)
+}
+
+func TestDecodeLimits(t *testing.T) {
+	t.Cleanup(func() { SetDecodeLimits(DefaultMaxImageMegapixels, DefaultMaxConcurrentDecodes) })
+
+	var buf bytes.Buffer
+	err := png.Encode(&buf, image.NewRGBA(image.Rect(0, 0, 200, 100)))
+	assert.NoError(t, err)
+
+	t.Run("Within budget", func(t *testing.T) {
+		SetDecodeLimits(1, 1)
+		img, err := BytesToImage(buf.Bytes())
+		assert.NoError(t, err)
+		assert.Equal(t, 200, img.Bounds().Dx())
+	})
+
+	t.Run("Over budget", func(t *testing.T) {
+		SetDecodeLimits(0.01, 1)
+		_, err := BytesToImage(buf.Bytes())
+		assert.ErrorIs(t, err, ErrImageTooLarge)
+	})
+
+	t.Run("Rendered size", func(t *testing.T) {
+		SetDecodeLimits(1, 1)
+		assert.NoError(t, CheckPixelBudget(1000, 1000))
+		assert.ErrorIs(t, CheckPixelBudget(1001, 1000), ErrImageTooLarge)
+	})
+
+	t.Run("Concurrent decodes", func(t *testing.T) {
+		SetDecodeLimits(1, 1)
+
+		release := AcquireDecodeSlot()
+
+		acquired := make(chan struct{})
+		go func() {
+			defer AcquireDecodeSlot()()
+			close(acquired)
+		}()
+
+		select {
+		case <-acquired:
+			t.Fatal("second decode should wait for the first to finish")
+		case <-time.After(50 * time.Millisecond):
+		}
+
+		release()
+
+		select {
+		case <-acquired:
+		case <-time.After(time.Second):
+			t.Fatal("second decode should start once the first has finished")
+		}
+	})
+}
+
+func TestImageToBytes(t *testing.T) {
+	img := image.NewNRGBA(image.Rect(0, 0, 64, 64))
+	for y := 0; y < 64; y++ {
+		for x := 0; x < 64; x++ {
+			img.Set(x, y, color.NRGBA{R: uint8(x * 4), G: uint8(y * 4), B: uint8((x * y) % 256), A: 255})
+		}
+	}
+
+	high, err := ImageToBytes(img, ImageFormatJPEG, 95)
+	assert.NoError(t, err)
+	assert.Equal(t, "image/jpeg", http.DetectContentType(high))
+
+	low, err := ImageToBytes(img, ImageFormatJPEG, 20)
+	assert.NoError(t, err)
+	assert.Less(t, len(low), len(high), "Lower quality should produce a smaller JPEG")
+
+	invalid, err := ImageToBytes(img, ImageFormatJPEG, 0)
+	assert.NoError(t, err)
+	assert.Equal(t, len(high), len(invalid), "Invalid quality should use the default")
+
+	pngBytes, err := ImageToBytes(img, ImageFormatPNG, 20)
+	assert.NoError(t, err)
+	assert.Equal(t, "image/png", http.DetectContentType(pngBytes))
+	assert.Equal(t, "image/png", ImageFormatMimeType(ImageFormatPNG))
+	assert.Equal(t, "image/jpeg", ImageFormatMimeType(ImageFormatJPEG))
+}
+
+func TestBlurRegions(t *testing.T) {
+	img := image.NewNRGBA(image.Rect(0, 0, 100, 100))
+	for y := 0; y < 100; y++ {
+		for x := 0; x < 100; x++ {
+			if (x+y)%2 == 0 {
+				img.Set(x, y, color.White)
+			} else {
+				img.Set(x, y, color.Black)
+			}
+		}
+	}
+
+	assert.Equal(t, img, BlurRegions(img, nil), "No regions should return the image untouched")
+
+	blurred := imaging.Clone(BlurRegions(img, []image.Rectangle{image.Rect(20, 20, 60, 60), image.Rect(90, 90, 150, 150)}))
+
+	assert.Equal(t, img.Bounds(), blurred.Bounds())
+	assert.Equal(t, img.NRGBAAt(5, 5), blurred.NRGBAAt(5, 5), "Pixels outside regions should not change")
+
+	inside := blurred.NRGBAAt(40, 40)
+	assert.InDelta(t, 128, int(inside.R), 20, "Checkerboard should be blurred to grey")
+
+	clipped := blurred.NRGBAAt(95, 95)
+	assert.InDelta(t, 128, int(clipped.R), 20, "Regions should be clipped to the image")
+}
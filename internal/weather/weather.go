@@ -3,10 +3,13 @@ package weather
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
 	"net/url"
+	"slices"
+	"strings"
 	"sync"
 	"time"
 
@@ -27,6 +30,10 @@ type WeatherLocation struct {
 	API  string
 	Unit string
 	Lang string
+	// UpdatedAt when the weather was last fetched successfully
+	UpdatedAt time.Time
+	// LastError the reason the last fetch failed, empty if it succeeded
+	LastError string
 	Weather
 }
 
@@ -129,6 +136,8 @@ func AddWeatherLocation(ctx context.Context, location config.WeatherLocation) {
 	newWeather, err := w.updateWeather()
 	if err != nil {
 		log.Error("Failed to update initial weather", "name", w.Name, "error", err)
+		w.LastError = weatherError(err)
+		weatherDataStore.Store(w.Name, *w)
 	} else {
 		weatherDataStore.Store(w.Name, newWeather)
 		log.Debug("Retrieved initial weather for", "name", w.Name)
@@ -144,6 +153,8 @@ func AddWeatherLocation(ctx context.Context, location config.WeatherLocation) {
 			newWeather, err := w.updateWeather()
 			if err != nil {
 				log.Error("Failed to update weather", "name", w.Name, "error", err)
+				w.LastError = weatherError(err)
+				weatherDataStore.Store(w.Name, *w)
 				continue
 			}
 			weatherDataStore.Store(w.Name, newWeather)
@@ -162,6 +173,32 @@ func CurrentWeather(name string) WeatherLocation {
 	return value.(WeatherLocation)
 }
 
+// Locations returns every monitored weather location, sorted by name.
+func Locations() []WeatherLocation {
+	var locations []WeatherLocation
+
+	weatherDataStore.Range(func(_, value any) bool {
+		locations = append(locations, value.(WeatherLocation))
+		return true
+	})
+
+	slices.SortFunc(locations, func(a, b WeatherLocation) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+
+	return locations
+}
+
+// weatherError returns a description of err that is safe to display.
+// Request errors include the request URL, which holds the API key, so only the cause is kept.
+func weatherError(err error) string {
+	var urlErr *url.Error
+	if errors.As(err, &urlErr) {
+		return urlErr.Err.Error()
+	}
+	return err.Error()
+}
+
 // updateWeather fetches new weather data from the OpenWeatherMap API for this location.
 // Returns the updated WeatherLocation and any error that occurred.
 func (w *WeatherLocation) updateWeather() (WeatherLocation, error) {
@@ -220,6 +257,8 @@ func (w *WeatherLocation) updateWeather() (WeatherLocation, error) {
 	}
 
 	w.Weather = newWeather
+	w.UpdatedAt = time.Now()
+	w.LastError = ""
 
 	return *w, nil
 }
@@ -11,6 +11,7 @@ import (
 	"github.com/charmbracelet/log"
 	"github.com/damongolding/immich-kiosk/internal/common"
 	"github.com/damongolding/immich-kiosk/internal/config"
+	"github.com/damongolding/immich-kiosk/internal/devices"
 	"github.com/damongolding/immich-kiosk/internal/immich"
 	"github.com/damongolding/immich-kiosk/internal/utils"
 )
@@ -22,6 +23,7 @@ const (
 	PreviousAsset                 WebhookEvent = "asset.previous"
 	PrefetchAsset                 WebhookEvent = "asset.prefetch"
 	CacheFlush                    WebhookEvent = "cache.flush"
+	RemoteCommand                 WebhookEvent = "remote.command"
 	UserWebhookTriggerInfoOverlay WebhookEvent = "user.webhook.trigger.info_overlay"
 )
 
@@ -38,6 +40,7 @@ type Payload struct {
 	AssetCount int                  `json:"assetCount"`
 	Assets     []immich.ImmichAsset `json:"assets"`
 	Config     config.Config        `json:"config"`
+	Command    *devices.Command     `json:"command,omitempty"`
 	Meta       Meta                 `json:"meta"`
 }
 
@@ -59,6 +62,17 @@ func newHTTPClient(timeout time.Duration) *http.Client {
 // event specifies which webhook event (NewAsset, PreviousAsset, etc) triggered this webhook.
 // viewData contains the images and other view context for the current request.
 func Trigger(requestData *common.RouteRequestData, KioskVersion string, event WebhookEvent, viewData common.ViewData) {
+	trigger(requestData, KioskVersion, event, viewData, nil)
+}
+
+// TriggerCommand sends the remote.command event to configured webhook endpoints
+// when a command is sent to the device in requestData.
+func TriggerCommand(requestData *common.RouteRequestData, KioskVersion string, command devices.Command) {
+	trigger(requestData, KioskVersion, RemoteCommand, common.ViewData{}, &command)
+}
+
+// trigger builds the payload for an event and posts it to every webhook configured for the event.
+func trigger(requestData *common.RouteRequestData, KioskVersion string, event WebhookEvent, viewData common.ViewData, command *devices.Command) {
 
 	if requestData == nil {
 		log.Error("invalid request data")
@@ -94,6 +108,7 @@ func Trigger(requestData *common.RouteRequestData, KioskVersion string, event We
 			AssetCount: len(images),
 			Assets:     images,
 			Config:     requestConfig,
+			Command:    command,
 			Meta: Meta{
 				Source:  "immich-kiosk",
 				Version: KioskVersion,
@@ -26,8 +26,10 @@ import (
 	"github.com/labstack/echo/v4/middleware"
 	"golang.org/x/time/rate"
 
+	"github.com/damongolding/immich-kiosk/internal/auth"
 	"github.com/damongolding/immich-kiosk/internal/common"
 	"github.com/damongolding/immich-kiosk/internal/config"
+	"github.com/damongolding/immich-kiosk/internal/devices"
 	"github.com/damongolding/immich-kiosk/internal/routes"
 	"github.com/damongolding/immich-kiosk/internal/utils"
 	"github.com/damongolding/immich-kiosk/internal/weather"
@@ -66,6 +68,18 @@ func main() {
 		log.Error("Failed to load config", "err", err)
 	}
 
+	utils.SetDecodeLimits(baseConfig.Kiosk.MaxImageMegapixels, baseConfig.Kiosk.MaxConcurrentDecodes)
+
+	if err := devices.Load(baseConfig.Kiosk.DevicesFile); err != nil {
+		log.Error("Failed to load devices", "path", baseConfig.Kiosk.DevicesFile, "err", err)
+	}
+	go devices.Persist(common.Context, time.Minute)
+
+	if err := auth.Load(baseConfig.Kiosk.TokensFile); err != nil {
+		log.Error("Failed to load tokens", "path", baseConfig.Kiosk.TokensFile, "err", err)
+	}
+	go auth.Persist(common.Context, time.Minute)
+
 	if baseConfig.Kiosk.WatchConfig {
 		log.Infof("Watching %s for changes", baseConfig.V.ConfigFileUsed())
 		baseConfig.WatchConfig(common.Context)
@@ -84,6 +98,46 @@ func main() {
 		log.Debug("🕐", "current_time", time.Now().Format(time.Kitchen), "current_zone", zone)
 	}
 
+	e := newServer(baseConfig)
+
+	for _, w := range baseConfig.WeatherLocations {
+		go weather.AddWeatherLocation(common.Context, w)
+	}
+
+	fmt.Printf("\nKiosk listening on port %s\n\n", versionStyle(fmt.Sprintf("%v", baseConfig.Kiosk.Port)))
+
+	go func() {
+		err = e.Start(fmt.Sprintf(":%v", baseConfig.Kiosk.Port))
+		if err != nil && err != http.ErrServerClosed {
+			log.Fatal(err)
+		}
+	}()
+
+	<-common.Context.Done()
+
+	fmt.Println("")
+	log.Info("Kiosk shutting down")
+	fmt.Println("")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	if err := e.Shutdown(ctx); err != nil {
+		log.Fatal(err)
+	}
+
+	if err := devices.Save(); err != nil {
+		log.Error("Failed to save devices", "err", err)
+	}
+
+	if err := auth.Save(); err != nil {
+		log.Error("Failed to save tokens", "err", err)
+	}
+
+}
+
+// newServer creates the web server, with its middleware and routes.
+func newServer(baseConfig *config.Config) *echo.Echo {
 	e := echo.New()
 	e.HideBanner = true
 	e.HidePort = true
@@ -95,25 +149,12 @@ func main() {
 	e.Use(middleware.GzipWithConfig(middleware.GzipConfig{
 		Level: 6,
 		Skipper: func(c echo.Context) bool {
-			return strings.Contains(c.Path(), "image")
+			return strings.Contains(c.Path(), "image") || strings.Contains(c.Path(), "frame") || c.Path() == "/events"
 		},
 	}))
 
-	if baseConfig.Kiosk.Password != "" {
-		e.Use(middleware.KeyAuthWithConfig(middleware.KeyAuthConfig{
-			Skipper: func(c echo.Context) bool {
-				// skip auth for assets
-				return strings.HasPrefix(c.Request().URL.String(), "/assets")
-			},
-			KeyLookup: "query:password,form:password",
-			Validator: func(queryPassword string, c echo.Context) (bool, error) {
-				return queryPassword == baseConfig.Kiosk.Password, nil
-			},
-			ErrorHandler: func(err error, c echo.Context) error {
-				return c.String(http.StatusUnauthorized, "Unauthorized")
-			},
-		}))
-	}
+	// nothing is checked until a password is set or a token is created
+	e.Use(routes.Authenticate(baseConfig))
 
 	// CSS cache busting
 	e.FileFS("/assets/css/kiosk.*.css", "frontend/public/assets/css/kiosk.css", public)
@@ -130,6 +171,8 @@ func main() {
 
 	e.GET("/image", routes.NewRawImage(baseConfig))
 
+	e.GET("/frame.jpg", routes.NewFrameImage(baseConfig))
+
 	e.POST("/image", routes.NewImage(baseConfig))
 
 	e.POST("/image/previous", routes.PreviousImage(baseConfig))
@@ -140,38 +183,69 @@ func main() {
 
 	e.GET("/sleep", routes.Sleep(baseConfig))
 
-	e.GET("/cache/flush", routes.FlushCache(baseConfig))
+	e.POST("/cache/flush", routes.FlushCache(baseConfig))
 
 	e.POST("/refresh/check", routes.RefreshCheck(baseConfig))
 
+	e.GET("/events", routes.Events(baseConfig))
+
 	e.POST("/webhooks", routes.Webhooks(baseConfig), middleware.RateLimiter(middleware.NewRateLimiterMemoryStore(rate.Limit(20))))
 
-	e.GET("/:redirect", routes.Redirect(baseConfig))
+	e.GET("/api/openapi.json", routes.OpenAPI)
 
-	for _, w := range baseConfig.WeatherLocations {
-		go weather.AddWeatherLocation(common.Context, w)
-	}
+	e.GET("/api/v1/next", routes.ApiNext(baseConfig))
 
-	fmt.Printf("\nKiosk listening on port %s\n\n", versionStyle(fmt.Sprintf("%v", baseConfig.Kiosk.Port)))
+	e.GET("/api/v1/previous", routes.ApiPrevious(baseConfig))
 
-	go func() {
-		err = e.Start(fmt.Sprintf(":%v", baseConfig.Kiosk.Port))
-		if err != nil && err != http.ErrServerClosed {
-			log.Fatal(err)
-		}
-	}()
+	e.POST("/api/v1/devices/:id/command", routes.RemoteDeviceCommand(baseConfig), routes.RequireAdmin(baseConfig))
 
-	<-common.Context.Done()
+	e.POST("/api/v1/clients/:name/command", routes.RemoteClientCommand(baseConfig), routes.RequireAdmin(baseConfig))
 
-	fmt.Println("")
-	log.Info("Kiosk shutting down")
-	fmt.Println("")
+	// the login page's login and logout forms share a CSRF token
+	loginCSRF := middleware.CSRFWithConfig(middleware.CSRFConfig{
+		TokenLookup:    "form:_csrf",
+		CookieName:     "_kiosk_login_csrf",
+		CookiePath:     "/",
+		CookieHTTPOnly: true,
+		CookieSameSite: http.SameSiteStrictMode,
+	})
 
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
-	defer cancel()
+	login := e.Group("/login", loginCSRF)
 
-	if err := e.Shutdown(ctx); err != nil {
-		log.Fatal(err)
+	login.GET("", routes.Login(baseConfig))
+
+	login.POST("", routes.LoginSubmit(baseConfig), middleware.RateLimiter(middleware.NewRateLimiterMemoryStore(rate.Limit(1))))
+
+	e.POST("/logout", routes.Logout, loginCSRF)
+
+	if routes.AdminEnabled(baseConfig) {
+		admin := e.Group("/admin",
+			routes.RequireAdmin(baseConfig),
+			middleware.CSRFWithConfig(middleware.CSRFConfig{
+				TokenLookup:    "form:_csrf",
+				CookieName:     "_kiosk_admin_csrf",
+				CookiePath:     "/admin",
+				CookieHTTPOnly: true,
+				CookieSameSite: http.SameSiteStrictMode,
+			}),
+		)
+
+		admin.GET("", routes.Admin(baseConfig))
+
+		admin.POST("/cache/flush", routes.AdminFlushCache(baseConfig))
+
+		admin.POST("/devices/:id/:command", routes.AdminDeviceCommand)
+
+		admin.GET("/assets/:id/thumbnail", routes.AdminAssetThumbnail(baseConfig))
+
+		admin.POST("/tokens", routes.AdminCreateToken(baseConfig))
+
+		admin.POST("/tokens/:id/revoke", routes.AdminRevokeToken)
+	} else {
+		log.Info("Admin dashboard disabled, set admin_password to enable it")
 	}
 
+	e.GET("/:redirect", routes.Redirect(baseConfig))
+
+	return e
 }
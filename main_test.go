@@ -0,0 +1,72 @@
+//go:build !generate
+// +build !generate
+
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/damongolding/immich-kiosk/internal/config"
+	"github.com/damongolding/immich-kiosk/internal/openapi"
+	"github.com/damongolding/immich-kiosk/internal/routes"
+)
+
+// TestRoutesDocumented tests every route the server registers is in the OpenAPI document and every documented route is registered
+func TestRoutesDocumented(t *testing.T) {
+	baseConfig := config.New()
+	// the admin dashboard is only registered when an admin password is set
+	baseConfig.Kiosk.AdminPassword = "admin-1234"
+
+	e := newServer(baseConfig)
+	doc := routes.OpenAPISpec()
+
+	registered := make(map[string]bool)
+
+	for _, route := range e.Routes() {
+		// static assets and echo's own not found routes are not part of the API
+		if strings.Contains(route.Path, "*") || (route.Method != http.MethodGet && route.Method != http.MethodPost) {
+			continue
+		}
+
+		path := openapi.Path(route.Path)
+		registered[route.Method+" "+path] = true
+
+		_, ok := doc.Operation(route.Method, path)
+		assert.True(t, ok, "%s %s is not documented", route.Method, path)
+	}
+
+	for path, item := range doc.Paths {
+		if item.Get != nil {
+			assert.True(t, registered[http.MethodGet+" "+path], "GET %s is documented but not registered", path)
+		}
+		if item.Post != nil {
+			assert.True(t, registered[http.MethodPost+" "+path], "POST %s is documented but not registered", path)
+		}
+	}
+}
+
+// TestMenuFlushCache tests the menu's cache flush only needs the Kiosk password, not the admin password
+func TestMenuFlushCache(t *testing.T) {
+	baseConfig := config.New()
+
+	e := newServer(baseConfig)
+
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/cache/flush", nil))
+	assert.Equal(t, http.StatusNoContent, rec.Code, "flushing should work without an admin password")
+
+	baseConfig.Kiosk.Password = "1234"
+
+	rec = httptest.NewRecorder()
+	e.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/cache/flush", nil))
+	assert.Equal(t, http.StatusUnauthorized, rec.Code, "flushing should need the Kiosk password once it is set")
+
+	rec = httptest.NewRecorder()
+	e.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/cache/flush?password=1234", nil))
+	assert.Equal(t, http.StatusNoContent, rec.Code)
+}